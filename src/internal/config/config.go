@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -12,9 +13,97 @@ type HTTPServer struct {
 	Addr string `yaml:"address" env-required:"true"`
 }
 
+// Analyzer limits how much of a target is read and analyzed, and caps the
+// options a request may ask for. A zero value disables the limit.
+type Analyzer struct {
+	MaxBodyBytes          int64   `yaml:"max_body_bytes" env:"ANALYZER_MAX_BODY_BYTES" env-default:"10485760"`
+	MaxDecompressionRatio float64 `yaml:"max_decompression_ratio" env:"ANALYZER_MAX_DECOMPRESSION_RATIO" env-default:"100"`
+	MaxDOMNodes           int     `yaml:"max_dom_nodes" env:"ANALYZER_MAX_DOM_NODES" env-default:"100000"`
+	MaxLinks              int     `yaml:"max_links" env:"ANALYZER_MAX_LINKS" env-default:"1000"`
+	// UserAgent is sent unless a request sets its own
+	UserAgent string `yaml:"user_agent" env:"ANALYZER_USER_AGENT" env-default:"web-analyzer/1.0"`
+	// DefaultTimeout applies to each HTTP request unless a request sets its own,
+	// which may not exceed MaxTimeout
+	DefaultTimeout    time.Duration `yaml:"default_timeout" env:"ANALYZER_DEFAULT_TIMEOUT" env-default:"30s"`
+	MaxTimeout        time.Duration `yaml:"max_timeout" env:"ANALYZER_MAX_TIMEOUT" env-default:"60s"`
+	MaxRedirects      int           `yaml:"max_redirects" env:"ANALYZER_MAX_REDIRECTS" env-default:"10"`
+	MaxRequestHeaders int           `yaml:"max_request_headers" env:"ANALYZER_MAX_REQUEST_HEADERS" env-default:"20"`
+}
+
+// SSRF controls which addresses the analyzer may connect to. Private, loopback,
+// link-local and metadata ranges are refused unless allowed here; denials take
+// precedence over allowances.
+type SSRF struct {
+	AllowCIDRs []string `yaml:"allow_cidrs" env:"SSRF_ALLOW_CIDRS"`
+	DenyCIDRs  []string `yaml:"deny_cidrs" env:"SSRF_DENY_CIDRS"`
+	AllowHosts []string `yaml:"allow_hosts" env:"SSRF_ALLOW_HOSTS"`
+	DenyHosts  []string `yaml:"deny_hosts" env:"SSRF_DENY_HOSTS"`
+}
+
+// Network routes the analyzer's outbound connections. Proxy is an http, https
+// or socks5 URL used for every host except those matching NoProxy (hostnames,
+// "*.example.com" patterns, IP addresses or CIDRs); ProxyPassword is a secret
+// reference like the credential secrets. Resolver is the address of a DNS server
+// used instead of the system resolver, and Hosts maps hostnames to fixed
+// addresses like /etc/hosts entries. An HTTP proxy resolves names itself, so
+// hosts with a fixed address bypass it, and it has to enforce its own egress
+// policy since it may resolve a name differently than the analyzer did.
+type Network struct {
+	Proxy         string            `yaml:"proxy" env:"ANALYZER_PROXY"`
+	ProxyUsername string            `yaml:"proxy_username" env:"ANALYZER_PROXY_USERNAME"`
+	ProxyPassword string            `yaml:"proxy_password" env:"ANALYZER_PROXY_PASSWORD"`
+	NoProxy       []string          `yaml:"no_proxy" env:"ANALYZER_NO_PROXY"`
+	Resolver      string            `yaml:"resolver" env:"ANALYZER_RESOLVER"`
+	Hosts         map[string]string `yaml:"hosts"`
+}
+
+// Credential authenticates the analyzer to the hosts it lists ("*.example.com"
+// matches subdomains, "example.com:8443" only that port). Type is basic, bearer, header or client_cert. Password,
+// Token and HeaderValue are secret references, never the secret itself:
+// "env:NAME" reads an environment variable and "file:/path" reads a file.
+// Header credentials are only sent over https unless AllowHTTP is set.
+type Credential struct {
+	Hosts       []string `yaml:"hosts"`
+	Type        string   `yaml:"type"`
+	Username    string   `yaml:"username"`
+	Password    string   `yaml:"password"`
+	Token       string   `yaml:"token"`
+	HeaderName  string   `yaml:"header_name"`
+	HeaderValue string   `yaml:"header_value"`
+	CertFile    string   `yaml:"cert_file"`
+	KeyFile     string   `yaml:"key_file"`
+	AllowHTTP   bool     `yaml:"allow_http"`
+}
+
+// Login is a form login that runs before the analysis of a request naming it.
+// The form is found by FormSelector, or by form detection when it is empty.
+// Fields are sent as given; SecretFields take "env:NAME" or "file:/path" references.
+type Login struct {
+	Name         string            `yaml:"name"`
+	URL          string            `yaml:"url"`
+	FormSelector string            `yaml:"form_selector"`
+	Fields       map[string]string `yaml:"fields"`
+	SecretFields map[string]string `yaml:"secret_fields"`
+	Success      LoginSuccess      `yaml:"success"`
+}
+
+// LoginSuccess decides whether a login worked, looking at the response after
+// redirects are followed. Every check that is set must pass; without any, the
+// login succeeds when the response is 2xx and no longer shows a login form.
+type LoginSuccess struct {
+	Status   int    `yaml:"status"`
+	Redirect string `yaml:"redirect"`
+	Selector string `yaml:"selector"`
+}
+
 type Config struct {
-	Env        string `yaml:"env" env:"ENV" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
+	Env         string `yaml:"env" env:"ENV" env-required:"true"`
+	HTTPServer  `yaml:"http_server"`
+	Analyzer    Analyzer     `yaml:"analyzer"`
+	SSRF        SSRF         `yaml:"ssrf"`
+	Network     Network      `yaml:"network"`
+	Credentials []Credential `yaml:"credentials"`
+	Logins      []Login      `yaml:"logins"`
 }
 
 func MustLoad() *Config {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"testing"
+	"time"
 )
 
 // setupEnv sets an environment variable and returns a function to restore it
@@ -34,6 +35,27 @@ func TestMustLoad(t *testing.T) {
 		}
 	})
 
+	t.Run("Analyzer limits default when not configured", func(t *testing.T) {
+		teardown := setupEnv(t, "CONFIG_PATH", "testdata/testconfig.yaml")
+		defer teardown()
+
+		got := MustLoad()
+		want := Analyzer{
+			MaxBodyBytes:          10485760,
+			MaxDecompressionRatio: 100,
+			MaxDOMNodes:           100000,
+			MaxLinks:              1000,
+			UserAgent:             "web-analyzer/1.0",
+			DefaultTimeout:        30 * time.Second,
+			MaxTimeout:            60 * time.Second,
+			MaxRedirects:          10,
+			MaxRequestHeaders:     20,
+		}
+		if got.Analyzer != want {
+			t.Errorf("Expected analyzer limits %+v, got %+v", want, got.Analyzer)
+		}
+	})
+
 	t.Run("Missing config file should exit", func(t *testing.T) {
 		teardown := setupEnv(t, "CONFIG_PATH", "nonexistent.yaml")
 		defer teardown()
@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/html"
+)
+
+// WCAG 2.1 success criteria referenced by the accessibility audit
+const (
+	wcagNonTextContent     = "1.1.1"
+	wcagInfoRelationships  = "1.3.1"
+	wcagPageTitled         = "2.4.2"
+	wcagLinkPurpose        = "2.4.4"
+	wcagLanguageOfPage     = "3.1.1"
+	wcagLabelsInstructions = "3.3.2"
+	wcagParsing            = "4.1.1"
+	wcagNameRoleValue      = "4.1.2"
+)
+
+var ariaRoles = toSet(
+	"alert", "alertdialog", "application", "article", "banner", "blockquote", "button", "caption", "cell",
+	"checkbox", "code", "columnheader", "combobox", "complementary", "contentinfo", "definition", "deletion",
+	"dialog", "directory", "document", "emphasis", "feed", "figure", "form", "generic", "grid", "gridcell",
+	"group", "heading", "img", "insertion", "link", "list", "listbox", "listitem", "log", "main", "marquee",
+	"math", "menu", "menubar", "menuitem", "menuitemcheckbox", "menuitemradio", "meter", "navigation", "none",
+	"note", "option", "paragraph", "presentation", "progressbar", "radio", "radiogroup", "region", "row",
+	"rowgroup", "rowheader", "scrollbar", "search", "searchbox", "separator", "slider", "spinbutton", "status",
+	"strong", "subscript", "superscript", "switch", "tab", "table", "tablist", "tabpanel", "term", "textbox",
+	"time", "timer", "toolbar", "tooltip", "tree", "treegrid", "treeitem",
+)
+
+var ariaAttributes = toSet(
+	"aria-activedescendant", "aria-atomic", "aria-autocomplete", "aria-braillelabel", "aria-brailleroledescription",
+	"aria-busy", "aria-checked", "aria-colcount", "aria-colindex", "aria-colindextext", "aria-colspan",
+	"aria-controls", "aria-current", "aria-describedby", "aria-description", "aria-details", "aria-disabled",
+	"aria-dropeffect", "aria-errormessage", "aria-expanded", "aria-flowto", "aria-grabbed", "aria-haspopup",
+	"aria-hidden", "aria-invalid", "aria-keyshortcuts", "aria-label", "aria-labelledby", "aria-level",
+	"aria-live", "aria-modal", "aria-multiline", "aria-multiselectable", "aria-orientation", "aria-owns",
+	"aria-placeholder", "aria-posinset", "aria-pressed", "aria-readonly", "aria-relevant", "aria-required",
+	"aria-roledescription", "aria-rowcount", "aria-rowindex", "aria-rowindextext", "aria-rowspan",
+	"aria-selected", "aria-setsize", "aria-sort", "aria-valuemax", "aria-valuemin", "aria-valuenow",
+	"aria-valuetext",
+)
+
+// Input types that don't need a visible label
+var unlabelledInputTypes = toSet("hidden", "submit", "reset", "button", "image")
+
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// auditAccessibility runs the DOM-level accessibility checks
+func auditAccessibility(doc *goquery.Document) *types.AccessibilityReport {
+	logrus.Debug("Auditing accessibility")
+	report := &types.AccessibilityReport{Findings: []types.Finding{}}
+
+	checkDocumentLanguage(doc, report)
+	checkDocumentTitle(doc, report)
+	checkImageAlternatives(doc, report)
+	checkFormLabels(doc, report)
+	checkEmptyControls(doc, report)
+	checkDuplicateIDs(doc, report)
+	checkARIA(doc, report)
+	checkHeadingLevels(doc, report)
+
+	logrus.Debug("Accessibility audit completed with ", len(report.Findings), " findings")
+	return report
+}
+
+// addAccessibilityFinding appends a finding pointing at the offending element
+func addAccessibilityFinding(report *types.AccessibilityReport, s *goquery.Selection, severity, check, wcag, format string, args ...interface{}) {
+	finding := types.Finding{
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+		WCAG:     wcag,
+	}
+	if s != nil {
+		finding.Selector = cssPath(s)
+	}
+	report.Findings = append(report.Findings, finding)
+}
+
+func checkDocumentLanguage(doc *goquery.Document, report *types.AccessibilityReport) {
+	root := doc.Find("html").First()
+	if strings.TrimSpace(root.AttrOr("lang", root.AttrOr("xml:lang", ""))) == "" {
+		addAccessibilityFinding(report, root, types.SeverityError, "html-lang", wcagLanguageOfPage, "The <html> element has no lang attribute")
+	}
+}
+
+func checkDocumentTitle(doc *goquery.Document, report *types.AccessibilityReport) {
+	if strings.TrimSpace(doc.Find("title").First().Text()) == "" {
+		addAccessibilityFinding(report, doc.Find("head").First(), types.SeverityError, "document-title", wcagPageTitled, "The document has no title")
+	}
+}
+
+func checkImageAlternatives(doc *goquery.Document, report *types.AccessibilityReport) {
+	doc.Find("img, input[type='image'], area").Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); ok || hasAccessibleNameAttr(s) || isHidden(s) {
+			return
+		}
+		addAccessibilityFinding(report, s, types.SeverityError, "image-alt", wcagNonTextContent, "<%s> has no alt text", goquery.NodeName(s))
+	})
+}
+
+func checkFormLabels(doc *goquery.Document, report *types.AccessibilityReport) {
+	labelled := make(map[string]bool)
+	doc.Find("label[for]").Each(func(i int, s *goquery.Selection) {
+		labelled[s.AttrOr("for", "")] = true
+	})
+
+	doc.Find("input, select, textarea").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "input" && unlabelledInputTypes[strings.ToLower(s.AttrOr("type", "text"))] {
+			return
+		}
+		if id, ok := s.Attr("id"); ok && labelled[id] {
+			return
+		}
+		if s.Closest("label").Length() > 0 || hasAccessibleNameAttr(s) || isHidden(s) {
+			return
+		}
+		addAccessibilityFinding(report, s, types.SeverityError, "form-label", wcagLabelsInstructions, "Form field %q has no associated label", s.AttrOr("name", goquery.NodeName(s)))
+	})
+}
+
+func checkEmptyControls(doc *goquery.Document, report *types.AccessibilityReport) {
+	doc.Find("a[href], button, [role='button'], [role='link']").Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) != "" || hasAccessibleNameAttr(s) || isHidden(s) {
+			return
+		}
+		if goquery.NodeName(s) == "button" && s.AttrOr("value", "") != "" {
+			return
+		}
+		// An image with alt text gives the control its name
+		if s.Find("img[alt], svg title").FilterFunction(func(i int, img *goquery.Selection) bool {
+			return strings.TrimSpace(img.AttrOr("alt", img.Text())) != ""
+		}).Length() > 0 {
+			return
+		}
+
+		if goquery.NodeName(s) == "a" || s.AttrOr("role", "") == "link" {
+			addAccessibilityFinding(report, s, types.SeverityError, "empty-link", wcagLinkPurpose, "Link has no discernible text")
+		} else {
+			addAccessibilityFinding(report, s, types.SeverityError, "empty-button", wcagNameRoleValue, "Button has no discernible text")
+		}
+	})
+}
+
+func checkDuplicateIDs(doc *goquery.Document, report *types.AccessibilityReport) {
+	seen := make(map[string]bool)
+	reported := make(map[string]bool)
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id == "" {
+			return
+		}
+		if seen[id] && !reported[id] {
+			reported[id] = true
+			addAccessibilityFinding(report, s, types.SeverityWarning, "duplicate-id", wcagParsing, "ID %q is used more than once", id)
+		}
+		seen[id] = true
+	})
+}
+
+func checkARIA(doc *goquery.Document, report *types.AccessibilityReport) {
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		node := s.Get(0)
+		for _, attr := range node.Attr {
+			key := strings.ToLower(attr.Key)
+			switch {
+			case key == "role":
+				for _, role := range strings.Fields(strings.ToLower(attr.Val)) {
+					if !ariaRoles[role] && !strings.HasPrefix(role, "doc-") {
+						addAccessibilityFinding(report, s, types.SeverityError, "aria-role", wcagNameRoleValue, "Invalid ARIA role %q", role)
+					}
+				}
+			case strings.HasPrefix(key, "aria-") && !ariaAttributes[key]:
+				addAccessibilityFinding(report, s, types.SeverityError, "aria-attribute", wcagNameRoleValue, "Invalid ARIA attribute %q", key)
+			}
+		}
+	})
+}
+
+func checkHeadingLevels(doc *goquery.Document, report *types.AccessibilityReport) {
+	for _, skip := range findHeadingSkips(doc) {
+		addAccessibilityFinding(report, skip.selection, types.SeverityWarning, "heading-order", wcagInfoRelationships, "Heading level skips from h%d to h%d", skip.from, skip.to)
+	}
+}
+
+// hasAccessibleNameAttr reports whether the element is named through ARIA or title
+func hasAccessibleNameAttr(s *goquery.Selection) bool {
+	for _, attr := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(s.AttrOr(attr, "")) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isHidden reports whether the element is removed from the accessibility tree
+func isHidden(s *goquery.Selection) bool {
+	return s.AttrOr("aria-hidden", "") == "true" || s.Closest("[hidden]").Length() > 0
+}
+
+// cssPath builds a CSS selector that uniquely locates the element in the document
+func cssPath(s *goquery.Selection) string {
+	var parts []string
+	for node := s.Get(0); node != nil && node.Type == html.ElementNode; node = node.Parent {
+		part := node.Data
+		index, count := 0, 0
+		if node.Parent != nil {
+			for sibling := node.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+				if sibling.Type == html.ElementNode && sibling.Data == node.Data {
+					count++
+					if sibling == node {
+						index = count
+					}
+				}
+			}
+		}
+		if count > 1 {
+			part += fmt.Sprintf(":nth-of-type(%d)", index)
+		}
+		parts = append([]string{part}, parts...)
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_auditAccessibility(t *testing.T) {
+	const page = `<html lang="en"><head><title>Accessible</title></head><body>%s</body></html>`
+	tests := []struct {
+		name     string
+		html     string
+		check    string
+		selector string
+		wcag     string
+	}{
+		{name: "Missing lang", html: `<html><head><title>T</title></head></html>`, check: "html-lang", selector: "html", wcag: "3.1.1"},
+		{name: "Missing title", html: `<html lang="en"><head></head></html>`, check: "document-title", selector: "html > head", wcag: "2.4.2"},
+		{name: "Image without alt", html: strings.Replace(page, "%s", `<p><img src="a.png"><img src="b.png" alt=""></p>`, 1), check: "image-alt", selector: "html > body > p > img:nth-of-type(1)", wcag: "1.1.1"},
+		{name: "Input without label", html: strings.Replace(page, "%s", `<input name="q"><label for="e">Email</label><input id="e">`, 1), check: "form-label", selector: "html > body > input:nth-of-type(1)", wcag: "3.3.2"},
+		{name: "Empty link", html: strings.Replace(page, "%s", `<a href="/x"><i class="icon"></i></a>`, 1), check: "empty-link", selector: "html > body > a", wcag: "2.4.4"},
+		{name: "Empty button", html: strings.Replace(page, "%s", `<button></button>`, 1), check: "empty-button", selector: "html > body > button", wcag: "4.1.2"},
+		{name: "Duplicate id", html: strings.Replace(page, "%s", `<div id="a"></div><span id="a"></span>`, 1), check: "duplicate-id", selector: "html > body > span", wcag: "4.1.1"},
+		{name: "Invalid role", html: strings.Replace(page, "%s", `<div role="buton"></div>`, 1), check: "aria-role", selector: "html > body > div", wcag: "4.1.2"},
+		{name: "Invalid aria attribute", html: strings.Replace(page, "%s", `<div aria-lable="x"></div>`, 1), check: "aria-attribute", selector: "html > body > div", wcag: "4.1.2"},
+		{name: "Skipped heading", html: strings.Replace(page, "%s", `<h1>A</h1><h3>B</h3>`, 1), check: "heading-order", selector: "html > body > h3", wcag: "1.3.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			got := auditAccessibility(doc)
+
+			var match *types.Finding
+			for i := range got.Findings {
+				if got.Findings[i].Check == tt.check {
+					match = &got.Findings[i]
+				}
+			}
+			if assert.NotNil(t, match, "expected %s finding in %v", tt.check, got.Findings) {
+				assert.Equal(t, tt.selector, match.Selector)
+				assert.Equal(t, tt.wcag, match.WCAG)
+			}
+			assert.Len(t, got.Findings, 1, "unexpected findings: %v", got.Findings)
+		})
+	}
+}
+
+func Test_auditAccessibility_Clean(t *testing.T) {
+	html := `<html lang="en"><head><title>Clean</title></head><body>
+		<h1>Title</h1><h2>Section</h2>
+		<img src="a.png" alt="Logo">
+		<label>Name <input name="name"></label>
+		<input type="text" aria-label="Search">
+		<input type="hidden" name="token">
+		<a href="/"><img src="home.png" alt="Home"></a>
+		<button aria-label="Close"></button>
+		<nav role="navigation" aria-label="Main"></nav>
+	</body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	got := auditAccessibility(doc)
+
+	assert.Empty(t, got.Findings)
+}
@@ -1,12 +1,13 @@
 package analyzer
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strings"
 	"sync"
 
@@ -15,11 +16,30 @@ import (
 	"github.com/vinothnada/web-analyzer/internal/types"
 )
 
-// URL validation regex
-var urlRegex = regexp.MustCompile(`^https?://`)
+// httpClient fetches pages and checks links; tests replace it with a client
+// that trusts their TLS server. Every connection goes through the SSRF guard
+// and every request carries the credential configured for its host.
+var httpClient = &http.Client{Transport: newCredentialTransport(guardedTransport())}
+
+// linkCheckConcurrency limits how many external links are checked at the same time
+const linkCheckConcurrency = 10
 
 // GetResults handles the incoming HTTP request to analyze a URL
 func GetResults(w http.ResponseWriter, r *http.Request) {
+	_, result, ok := handleAnalysisRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Return the analysis result as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleAnalysisRequest runs the steps shared by every analysis endpoint: CORS,
+// method check, payload parsing, URL validation and the analysis itself. It
+// writes the error response itself and reports false when the caller should stop.
+func handleAnalysisRequest(w http.ResponseWriter, r *http.Request) (string, *types.AnalyzeResultes, bool) {
 	logrus.Info("Setting response headers")
 	setResponseHeaders(w)
 
@@ -27,7 +47,7 @@ func GetResults(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		logrus.Info("Handling OPTIONS request")
 		handleOptionsRequest(w)
-		return
+		return "", nil, false
 	}
 
 	logrus.Info("Received analysis request")
@@ -36,7 +56,7 @@ func GetResults(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logrus.Warn("Invalid request method")
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		return
+		return "", nil, false
 	}
 
 	// Parse the request payload
@@ -44,31 +64,59 @@ func GetResults(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		logrus.Error("Failed to parse payload: ", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return "", nil, false
 	}
 
-	// Validate the URL format
-	if !isValidURL(payload.URL) {
-		logrus.Warn("Invalid URL format: ", payload.URL)
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
-		return
+	// Validate the URL and analyze its canonical form
+	canonicalURL, err := normalizeURL(payload.URL)
+	if err != nil {
+		logrus.Warn("Invalid URL format: ", payload.URL, ": ", err)
+		http.Error(w, "Invalid URL format: "+err.Error(), http.StatusBadRequest)
+		return "", nil, false
+	}
+	payload.URL = canonicalURL
+
+	opts, err := resolveOptions(payload)
+	if err != nil {
+		logrus.Warn("Invalid analysis options: ", err)
+		http.Error(w, "Invalid analysis options: "+err.Error(), http.StatusBadRequest)
+		return "", nil, false
 	}
 
 	logrus.Info("Starting page analysis for URL: ", payload.URL)
 
 	// Analyze the page
-	result, err := analyzePage(payload.URL)
+	result, err := runAnalysis(opts)
 	if err != nil {
 		logrus.Error("Error analyzing page: ", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		http.Error(w, err.Error(), errorStatus(err))
+		return "", nil, false
+	}
+	if payload.PageWeight {
+		result.PageWeight = auditPageWeight(payload.URL, result.Resources, opts)
 	}
 
 	logrus.Info("Successfully analyzed page")
+	return payload.URL, result, true
+}
 
-	// Return the analysis result as JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+// errorStatus maps an analysis error onto the HTTP status returned to the client
+func errorStatus(err error) int {
+	var (
+		tlsErr         *TLSError
+		contentTypeErr *UnsupportedContentTypeError
+		blockedErr     *BlockedTargetError
+		loginErr       *LoginError
+	)
+	switch {
+	case errors.As(err, &blockedErr):
+		return http.StatusForbidden
+	case errors.As(err, &tlsErr), errors.As(err, &loginErr):
+		return http.StatusBadGateway
+	case errors.As(err, &contentTypeErr):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
 }
 
 // setResponseHeaders sets the necessary CORS headers for the response
@@ -96,10 +144,11 @@ func parsePayload(r *http.Request) (types.RequestPayload, error) {
 	return payload, nil
 }
 
-// isValidURL checks if the provided URL is valid using regex
+// isValidURL checks if the provided URL is an http or https URL with a host
 func isValidURL(targetURL string) bool {
 	logrus.Debug("Validating URL: ", targetURL)
-	valid := urlRegex.MatchString(targetURL)
+	_, err := normalizeURL(targetURL)
+	valid := err == nil
 
 	if valid {
 		logrus.Debug("URL is valid")
@@ -111,119 +160,196 @@ func isValidURL(targetURL string) bool {
 
 // analyzePage analyzes the content of the page at the given URL
 func analyzePage(targetURL string) (*types.AnalyzeResultes, error) {
+	return runAnalysis(defaultOptions(targetURL))
+}
+
+// runAnalysis analyzes the page of the options the way they ask for
+func runAnalysis(opts *analysisOptions) (*types.AnalyzeResultes, error) {
+	targetURL := opts.pageURL.String()
+	var login *types.LoginReport
+	if opts.login != nil {
+		var err error
+		if login, err = performLogin(opts); err != nil {
+			return nil, err
+		}
+	}
 	logrus.Info("Fetching URL: ", targetURL)
-	resp, err := fetchURL(targetURL)
+	timer := newRequestTimer()
+	resp, err := fetchTimedURL(targetURL, timer, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	doc, err := parseHTML(resp.Body)
+	// Read the whole body before parsing so the download phase excludes parsing
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	timing := timer.finish(timingTargetPage)
+
+	parsedURL := opts.pageURL
+	mediaType := detectMediaType(resp.Header.Get("Content-Type"), body)
+	var result *types.AnalyzeResultes
+	switch {
+	case htmlMediaTypes[mediaType]:
+		result, err = analyzeHTML(body, resp, parsedURL, opts)
+	case documentAnalyzers[mediaType] != nil:
+		result, err = documentAnalyzers[mediaType](body, resp, parsedURL, opts)
+	default:
+		logrus.Warn("Unsupported content type: ", mediaType)
+		return nil, &UnsupportedContentTypeError{ContentType: mediaType}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	logrus.Info("Extracting data from page")
-	result := &types.AnalyzeResultes{Headings: make(map[string]int)}
-
-	var waitGroup sync.WaitGroup
-	internalLinks, externalLinks := 0, 0
-	accessibleExternalLinks, brokenExternalLinks := 0, 0
-	linksChan := make(chan string)
-	statusChan := make(chan string)
-
-	result.HTMLVersion = getHtmlVersion(doc)
-	result.Title = extractTitle(doc)
-	result.Headings = countHeadings(doc)
-
-	parsedURL, _ := url.Parse(targetURL)
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if !exists {
-			return
-		}
-
-		hrefParsed, err := url.Parse(href)
-		if err != nil {
-			return
-		}
-
-		if hrefParsed.Host == "" || hrefParsed.Host == parsedURL.Host {
-			internalLinks++
-		} else {
-			externalLinks++
-			waitGroup.Add(1)
-			go func(link string) {
-				defer waitGroup.Done()
-				linksChan <- link
-			}(href)
-		}
-	})
-
-	go func() {
-		waitGroup.Wait()
-		close(linksChan)
-	}()
-
-	go func() {
-		for link := range linksChan {
-			statusChan <- checkLinkAccessibility(link)
-		}
-		close(statusChan)
-	}()
-
-	for status := range statusChan {
-		if status == "accessible" {
-			accessibleExternalLinks++
-		} else {
-			brokenExternalLinks++
-		}
+	if reason := bodyTruncation(resp); reason != "" {
+		markTruncated(result, "%s", reason)
 	}
 
-	result.InternalLinks = internalLinks
-	result.ExternalLinks = externalLinks
-	result.AccessibleExternalLinks = accessibleExternalLinks
-	result.BrokenExternalLinks = brokenExternalLinks
-	result.HasLoginForm = hasLoginForm(doc)
+	// Checks on the response itself apply whatever the content is
+	result.URL = targetURL
+	result.ContentType = mediaType
+	result.Timing = timing
+	result.Login = login
+	if opts.enabled("securityHeaders") {
+		result.SecurityHeaders = auditSecurityHeaders(resp.Header, parsedURL)
+	}
+	if opts.enabled("cookies") {
+		result.Cookies = auditCookies(resp)
+	}
+	if opts.enabled("tls") {
+		result.TLS = inspectTLS(resp)
+	}
 
 	logrus.Info("Page analysis completed successfully")
 	return result, nil
 }
 
-func getHtmlVersion(doc *goquery.Document) string {
-	rawHTML, err := doc.Html()
+// analyzeHTML extracts the page data from an HTML or XHTML body
+func analyzeHTML(body []byte, resp *http.Response, parsedURL *url.URL, opts *analysisOptions) (*types.AnalyzeResultes, error) {
+	body, encoding := decodeBody(body, resp.Header.Get("Content-Type"))
+	doc, err := parseHTML(bytes.NewReader(body))
 	if err != nil {
-		logrus.Error("Error extracting HTML content:", err)
-		return ""
+		return nil, err
+	}
+
+	logrus.Info("Extracting data from page")
+	result := &types.AnalyzeResultes{Headings: make(map[string]int), Encoding: encoding}
+	if pruneDOM(doc.Nodes[0]) {
+		markTruncated(result, "only the first %d DOM nodes were analyzed", limits.MaxDOMNodes)
+	}
+
+	result.Doctype = parseDoctype(doc)
+	result.HTMLVersion = htmlVersionLabel(result.Doctype)
+	result.Title = extractTitle(doc)
+	if opts.enabled("headings") {
+		result.Headings = countHeadings(doc)
+		result.HeadingOutline = buildHeadingOutline(doc)
+		// Skipped levels belong to the accessibility audit, the outline only reports them without it
+		if !opts.enabled("accessibility") {
+			addSkippedLevelFindings(doc, result.HeadingOutline)
+		}
 	}
-	rawHTML = strings.TrimSpace(rawHTML)
-	if strings.Contains(rawHTML, "<!DOCTYPE html>") {
-		return "HTML5"
+	if opts.enabled("links") {
+		checkLinks(result, collectLinks(doc, parsedURL), opts)
 	}
-	if strings.Contains(rawHTML, "<!DOCTYPE HTML PUBLIC") {
-		return "HTML 4"
+	if opts.enabled("forms") {
+		result.Forms = inventoryForms(doc, parsedURL)
+		auditFormSecurity(result.Forms, parsedURL)
+		result.HasLoginForm = isLoginPage(result.Forms)
 	}
-	if strings.Contains(rawHTML, "<!DOCTYPE html PUBLIC") {
-		return "XHTML"
+	if opts.enabled("seo") {
+		result.SEO = auditSEO(doc, parsedURL, resp.Header, opts)
+	}
+	if opts.enabled("csp") {
+		result.CSP = evaluateCSP(doc, resp.Header, parsedURL)
+	}
+	if opts.enabled("mixedContent") {
+		result.MixedContent = detectMixedContent(doc, resp.Header, parsedURL)
+	}
+	if opts.enabled("resources") {
+		result.Resources = inventoryResources(doc, parsedURL)
+	}
+	if opts.enabled("structuredData") {
+		result.StructuredData = extractStructuredData(doc)
+	}
+	if opts.enabled("accessibility") {
+		result.Accessibility = auditAccessibility(doc)
+	}
+	return result, nil
+}
+
+// countLinks totals the checked links of the result by type and status
+func countLinks(result *types.AnalyzeResultes) {
+	for _, link := range result.Links {
+		switch {
+		case link.Type == types.LinkTypeInternal:
+			result.InternalLinks++
+		case link.Status == types.LinkStatusAccessible:
+			result.ExternalLinks++
+			result.AccessibleExternalLinks++
+		case link.Status == types.LinkStatusUnchecked:
+			result.ExternalLinks++
+		default:
+			result.ExternalLinks++
+			result.BrokenExternalLinks++
+		}
 	}
-	return "Unknown HTML version"
+}
+
+// getHtmlVersion identifies the HTML version from the document's doctype
+func getHtmlVersion(doc *goquery.Document) string {
+	return htmlVersionLabel(parseDoctype(doc))
 }
 
 // fetchURL sends a GET request to fetch the URL's content
 func fetchURL(targetURL string) (*http.Response, error) {
+	return fetchTimedURL(targetURL, nil, defaultOptions(targetURL))
+}
+
+// fetchTimedURL is fetchURL reporting the phases of the request to timer, when set
+func fetchTimedURL(targetURL string, timer *requestTimer, opts *analysisOptions) (*http.Response, error) {
 	logrus.Debug("Sending GET request to URL: ", targetURL)
-	resp, err := http.Get(targetURL)
+	req, err := opts.newRequest(http.MethodGet, targetURL, nil)
 	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	if timer != nil {
+		req = req.WithContext(timer.trace(req.Context()))
+	}
+	// Asking for compression ourselves disables transparent decompression, so
+	// limitedBody can see the compressed size
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		if tlsErr := classifyTLSError(err); tlsErr != nil {
+			logrus.Warn("TLS error fetching URL: ", tlsErr)
+			return nil, tlsErr
+		}
 		logrus.Error("Failed to fetch URL: ", err)
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
 
 	// Check if the status code is OK (200)
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		logrus.Warn("URL returned non-OK status: ", resp.StatusCode)
+		if location := resp.Header.Get("Location"); location != "" {
+			return nil, fmt.Errorf("URL returned status code %d redirecting to %s", resp.StatusCode, location)
+		}
 		return nil, fmt.Errorf("URL returned status code %d", resp.StatusCode)
 	}
 
+	body, err := newLimitedBody(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = body
+
 	logrus.Debug("URL fetched successfully with status code: ", resp.StatusCode)
 	return resp, nil
 }
@@ -265,36 +391,129 @@ func countHeadings(doc *goquery.Document) map[string]int {
 	return headings
 }
 
-func checkLinkAccessibility(link string) string {
-	resp, err := http.Head(link)
-	if err != nil {
-		logrus.Debug("Error checking link:", link, " Error:", err)
-		return "broken"
+// collectLinks lists every anchor with an href, classified as internal or external
+func collectLinks(doc *goquery.Document, pageURL *url.URL) []types.LinkResult {
+	logrus.Debug("Collecting links")
+	var links []types.LinkResult
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists {
+			return
+		}
+
+		hrefParsed, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+
+		link := types.LinkResult{
+			Href:       href,
+			AnchorText: strings.TrimSpace(s.Text()),
+			Status:     types.LinkStatusUnchecked,
+		}
+		if hrefParsed.Host == "" || hrefParsed.Host == pageURL.Host {
+			link.Type = types.LinkTypeInternal
+		} else {
+			link.Type = types.LinkTypeExternal
+		}
+		links = append(links, link)
+	})
+	return links
+}
+
+// checkLinkStatuses checks the accessibility of the links the options select
+// concurrently and records the outcome in place. Internal links are resolved
+// against the page first, and links with the same canonical URL are checked once.
+func checkLinkStatuses(links []types.LinkResult, opts *analysisOptions) {
+	groups := make(map[string][]int)
+	var keys []string
+	for i := range links {
+		target := links[i].Href
+		switch {
+		case links[i].Type == types.LinkTypeExternal && opts.checkExternalLinks:
+		case links[i].Type == types.LinkTypeInternal && opts.checkInternalLinks && opts.pageURL != nil:
+			ref, err := url.Parse(target)
+			if err != nil {
+				continue
+			}
+			target = opts.pageURL.ResolveReference(ref).String()
+		default:
+			continue
+		}
+		key, err := normalizeURL(target)
+		if err != nil {
+			key = target
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	forEachConcurrently(len(keys), func(i int) {
+		group := groups[keys[i]]
+		status, timing := checkLink(keys[i], opts)
+		for _, idx := range group {
+			links[idx].Status, links[idx].Timing = status, timing
+		}
+	})
+}
+
+// forEachConcurrently calls work for every index below n, running at most
+// linkCheckConcurrency calls at the same time
+func forEachConcurrently(n int, work func(i int)) {
+	indexes := make(chan int)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < linkCheckConcurrency; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for idx := range indexes {
+				work(idx)
+			}
+		}()
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return "accessible"
+	for i := 0; i < n; i++ {
+		indexes <- i
 	}
-	return "broken"
+	close(indexes)
+	waitGroup.Wait()
 }
 
-// hasLoginForm checks if the page contains a login form
-func hasLoginForm(doc *goquery.Document) bool {
-	logrus.Debug("Checking for login form")
+// checkLinkAccessibility sends a HEAD request to the link and reports whether it is reachable
+func checkLinkAccessibility(link string) string {
+	status, _ := checkLink(link, defaultOptions(link))
+	return status
+}
 
-	// Check for password field
-	if doc.Find("input[type='password']").Length() > 0 {
-		return true
+// checkLink is checkLinkAccessibility that also returns the timing of the request
+// when a response was received
+func checkLink(link string, opts *analysisOptions) (string, *types.RequestTiming) {
+	req, err := opts.newRequest(http.MethodHead, link, nil)
+	if err != nil {
+		logrus.Debug("Error checking link:", link, " Error:", err)
+		return types.LinkStatusBroken, nil
+	}
+	timer := newRequestTimer()
+	resp, err := opts.client().Do(req.WithContext(timer.trace(req.Context())))
+	if err != nil {
+		logrus.Debug("Error checking link:", link, " Error:", err)
+		return types.LinkStatusBroken, nil
 	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	timing := timer.finish(timingTargetLink)
 
-	// Check for common social media login buttons (Facebook, Google, etc.)
-	if doc.Find("button, a").FilterFunction(func(i int, s *goquery.Selection) bool {
-		text := s.Text()
-		return strings.Contains(strings.ToLower(text), "login with") || strings.Contains(strings.ToLower(text), "sign in with")
-	}).Length() > 0 {
-		return true
+	// A redirect that is not followed still shows the link leads somewhere
+	if (resp.StatusCode >= 200 && resp.StatusCode < 300) || (!opts.followRedirects && resp.StatusCode >= 300 && resp.StatusCode < 400) {
+		return types.LinkStatusAccessible, timing
 	}
-	return false
+	return types.LinkStatusBroken, timing
+}
 
+// hasLoginForm checks if the page contains a login form, either a form
+// classified as login or OAuth sign-in buttons
+func hasLoginForm(doc *goquery.Document) bool {
+	logrus.Debug("Checking for login form")
+	return isLoginPage(inventoryForms(doc, nil))
 }
@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// charsetPrescanLength is how far into the body browsers look for <meta charset>
+const charsetPrescanLength = 1024
+
+// metaCharsetRegex matches both <meta charset> and the charset parameter of
+// <meta http-equiv="Content-Type" content="...">
+var metaCharsetRegex = regexp.MustCompile(`(?is)<meta\s[^>]*?charset\s*=\s*["']?\s*([a-z0-9_.:-]+)`)
+
+// charsetSkipRegex matches comments and scripts, whose <meta> tags declare nothing
+var charsetSkipRegex = regexp.MustCompile(`(?is)<!--.*?(-->|$)|<script\b.*?(</script\s*>|$)`)
+
+var byteOrderMarks = []struct {
+	bom   []byte
+	label string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+var encodingSourceNames = map[string]string{
+	types.EncodingSourceBOM:    "The byte order mark",
+	types.EncodingSourceHeader: "The Content-Type header",
+	types.EncodingSourceMeta:   "The <meta> charset",
+}
+
+// decodeBody works out the character encoding of the page the way a browser
+// would (BOM, then Content-Type, then <meta>, then sniffing) and returns the
+// body transcoded to UTF-8
+func decodeBody(body []byte, contentType string) ([]byte, *types.EncodingReport) {
+	logrus.Debug("Detecting character encoding")
+	report := &types.EncodingReport{Findings: []types.Finding{}}
+
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(body, mark.bom) {
+			report.BOM = mark.label
+			body = body[len(mark.bom):]
+			break
+		}
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		report.HeaderCharset = strings.TrimSpace(params["charset"])
+	}
+	// Like browsers, only the first bytes are searched for the charset used; a
+	// declaration further in is only reported
+	lateMeta := ""
+	if loc := findMetaCharset(body[:min(len(body), charsetPrescanLength)]); loc != nil {
+		report.MetaCharset = string(body[loc[2]:loc[3]])
+	} else if loc := findMetaCharset(body); loc != nil {
+		lateMeta = string(body[loc[2]:loc[3]])
+	}
+
+	enc := selectEncoding(report)
+	if enc == nil {
+		report.Source = types.EncodingSourceSniffed
+		enc, report.Encoding = charset.Lookup("windows-1252")
+		if utf8.Valid(body) {
+			enc, report.Encoding = charset.Lookup("utf-8")
+		}
+		if report.HeaderCharset == "" && report.MetaCharset == "" && lateMeta == "" {
+			addFinding(&report.Findings, types.SeverityWarning, "charset-missing", "No character encoding is declared, %s was guessed", report.Encoding)
+		}
+	}
+	checkEncodingDeclarations(report, lateMeta)
+
+	if report.Encoding == "utf-8" {
+		if !utf8.Valid(body) {
+			addFinding(&report.Findings, types.SeverityWarning, "charset-invalid", "The page is decoded as UTF-8 but contains invalid byte sequences")
+		}
+		return body, report
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		addFinding(&report.Findings, types.SeverityWarning, "charset-invalid", "The page could not be decoded as %s: %v", report.Encoding, err)
+		return body, report
+	}
+	logrus.Debug("Transcoded page from ", report.Encoding, " to UTF-8")
+	return decoded, report
+}
+
+// selectEncoding picks the first usable declaration in order of precedence and
+// records it in the report; it returns nil when none can be used
+func selectEncoding(report *types.EncodingReport) encoding.Encoding {
+	declarations := []struct {
+		source string
+		label  string
+	}{
+		{types.EncodingSourceBOM, report.BOM},
+		{types.EncodingSourceHeader, report.HeaderCharset},
+		{types.EncodingSourceMeta, report.MetaCharset},
+	}
+	for _, declaration := range declarations {
+		if declaration.label == "" {
+			continue
+		}
+		enc, name := charset.Lookup(declaration.label)
+		if enc == nil {
+			addFinding(&report.Findings, types.SeverityWarning, "charset-unknown", "%s declares the unknown encoding %q", encodingSourceNames[declaration.source], declaration.label)
+			continue
+		}
+		// A document cannot declare itself UTF-16 from inside, the spec reads it as UTF-8
+		if declaration.source == types.EncodingSourceMeta && strings.HasPrefix(name, "utf-16") {
+			enc, name = charset.Lookup("utf-8")
+		}
+		report.Encoding, report.Source = name, declaration.source
+		return enc
+	}
+	return nil
+}
+
+// findMetaCharset returns the submatch indexes of the first <meta> charset
+// declaration outside comments and scripts, or nil
+func findMetaCharset(body []byte) []int {
+	skipped := charsetSkipRegex.FindAllIndex(body, -1)
+	for _, loc := range metaCharsetRegex.FindAllSubmatchIndex(body, -1) {
+		inside := false
+		for _, skip := range skipped {
+			if loc[0] >= skip[0] && loc[0] < skip[1] {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return loc
+		}
+	}
+	return nil
+}
+
+func checkEncodingDeclarations(report *types.EncodingReport, lateMeta string) {
+	canonical := func(label string) string {
+		_, name := charset.Lookup(label)
+		return name
+	}
+	header, meta := canonical(report.HeaderCharset), canonical(report.MetaCharset)
+
+	if report.Source == types.EncodingSourceBOM && ((header != "" && header != report.Encoding) || (meta != "" && meta != report.Encoding)) {
+		addFinding(&report.Findings, types.SeverityInfo, "charset-conflict", "The byte order mark overrides the declared encoding, the page is read as %s", report.Encoding)
+	}
+	if header != "" && meta != "" && header != meta {
+		addFinding(&report.Findings, types.SeverityWarning, "charset-conflict", "Content-Type declares %s but <meta> declares %s, the page is read as %s", header, meta, report.Encoding)
+	}
+	if lateMeta != "" {
+		addFinding(&report.Findings, types.SeverityWarning, "charset-late", "The <meta> charset %q appears after the first %d bytes and is ignored", lateMeta, charsetPrescanLength)
+	}
+}
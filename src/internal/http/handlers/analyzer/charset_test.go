@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	assert.NoError(t, err)
+	return b
+}
+
+func Test_decodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+		encoding    string
+		source      string
+		severity    string
+		check       string
+	}{
+		{name: "Header charset", body: encode(t, japanese.ShiftJIS, "<title>日本語</title>"), contentType: "text/html; charset=Shift_JIS", want: "<title>日本語</title>", encoding: "shift_jis", source: types.EncodingSourceHeader},
+		{name: "Meta charset", body: encode(t, simplifiedchinese.GBK, `<meta charset="gbk"><title>中文</title>`), contentType: "text/html", want: `<meta charset="gbk"><title>中文</title>`, encoding: "gbk", source: types.EncodingSourceMeta},
+		{name: "Http-equiv charset", body: encode(t, charmap.Windows1252, `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><p>café</p>`), want: `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><p>café</p>`, encoding: "windows-1252", source: types.EncodingSourceMeta},
+		{name: "BOM wins", body: append([]byte{0xEF, 0xBB, 0xBF}, "<p>café</p>"...), contentType: "text/html; charset=iso-8859-1", want: "<p>café</p>", encoding: "utf-8", source: types.EncodingSourceBOM, severity: types.SeverityInfo, check: "charset-conflict"},
+		{name: "Header and meta conflict", body: []byte(`<meta charset="iso-8859-1"><p>café</p>`), contentType: "text/html; charset=utf-8", want: `<meta charset="iso-8859-1"><p>café</p>`, encoding: "utf-8", source: types.EncodingSourceHeader, severity: types.SeverityWarning, check: "charset-conflict"},
+		{name: "Sniffed UTF-8", body: []byte("<p>café</p>"), want: "<p>café</p>", encoding: "utf-8", source: types.EncodingSourceSniffed, severity: types.SeverityWarning, check: "charset-missing"},
+		{name: "Sniffed legacy", body: []byte("<p>caf\xe9</p>"), want: "<p>café</p>", encoding: "windows-1252", source: types.EncodingSourceSniffed, severity: types.SeverityWarning, check: "charset-missing"},
+		{name: "Unknown label", body: []byte("<p>café</p>"), contentType: "text/html; charset=klingon", want: "<p>café</p>", encoding: "utf-8", source: types.EncodingSourceSniffed, severity: types.SeverityWarning, check: "charset-unknown"},
+		{name: "Late meta charset", body: []byte(strings.Repeat(" ", charsetPrescanLength) + `<meta charset="iso-8859-1"><p>café</p>`), want: strings.Repeat(" ", charsetPrescanLength) + `<meta charset="iso-8859-1"><p>café</p>`, encoding: "utf-8", source: types.EncodingSourceSniffed, severity: types.SeverityWarning, check: "charset-late"},
+		{name: "Meta in comment", body: []byte(`<!-- <meta charset="gbk"> --><meta charset="utf-8"><p>café</p>`), want: `<!-- <meta charset="gbk"> --><meta charset="utf-8"><p>café</p>`, encoding: "utf-8", source: types.EncodingSourceMeta},
+		{name: "Meta in script", body: []byte(`<script>document.write('<meta charset="gbk">')</script><p>café</p>`), want: `<script>document.write('<meta charset="gbk">')</script><p>café</p>`, encoding: "utf-8", source: types.EncodingSourceSniffed, severity: types.SeverityWarning, check: "charset-missing"},
+		{name: "Invalid UTF-8", body: []byte("<p>caf\xe9</p>"), contentType: "text/html; charset=utf-8", want: "<p>caf\xe9</p>", encoding: "utf-8", source: types.EncodingSourceHeader, severity: types.SeverityWarning, check: "charset-invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, report := decodeBody(tt.body, tt.contentType)
+
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.encoding, report.Encoding)
+			assert.Equal(t, tt.source, report.Source)
+			if tt.check != "" {
+				assert.True(t, hasFinding(report.Findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, report.Findings)
+			} else {
+				assert.Empty(t, report.Findings)
+			}
+		})
+	}
+}
+
+func Test_analyzePage_ShiftJIS(t *testing.T) {
+	page := encode(t, japanese.ShiftJIS, "<html><head><title>ようこそ</title></head><body><h1>見出し</h1></body></html>")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+		w.Write(page)
+	}))
+	defer ts.Close()
+
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "ようこそ", result.Title)
+	assert.Equal(t, "見出し", result.HeadingOutline.Headings[0].Text)
+	assert.Equal(t, "shift_jis", result.Encoding.Encoding)
+	assert.Equal(t, "Shift_JIS", result.Encoding.HeaderCharset)
+}
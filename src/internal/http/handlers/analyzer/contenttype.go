@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// htmlMediaTypes are parsed as HTML documents
+var htmlMediaTypes = toSet("text/html", "application/xhtml+xml")
+
+// documentAnalyzers extract what they can from the non-HTML media types the
+// analyzer supports, filling the same result shape as HTML pages
+var documentAnalyzers = map[string]func(body []byte, resp *http.Response, pageURL *url.URL, opts *analysisOptions) (*types.AnalyzeResultes, error){
+	"text/plain":      analyzePlainText,
+	"application/pdf": analyzePDF,
+}
+
+var textURLRegex = regexp.MustCompile(`https?://[^\s<>"'(){}\[\]]+`)
+
+// UnsupportedContentTypeError is returned when the target is neither HTML nor
+// one of the document types the analyzer understands
+type UnsupportedContentTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q", e.ContentType)
+}
+
+// detectMediaType returns the media type the body is analyzed as. The declared
+// Content-Type is trusted unless it is missing, generic, or labels binary
+// content as text, in which case the sniffed type is used.
+func detectMediaType(contentType string, body []byte) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	declared, _, err := mime.ParseMediaType(contentType)
+	switch {
+	case err != nil, declared == "application/octet-stream":
+		logrus.Debug("Using sniffed content type ", sniffed, " instead of ", contentType)
+		return sniffed
+	case declared == "text/plain" && !strings.HasPrefix(sniffed, "text/"):
+		logrus.Debug("Binary content labeled as text/plain, sniffed ", sniffed)
+		return sniffed
+	}
+	return declared
+}
+
+// analyzePlainText reports the first line as the title and the URLs found in the text as links
+func analyzePlainText(body []byte, resp *http.Response, pageURL *url.URL, opts *analysisOptions) (*types.AnalyzeResultes, error) {
+	logrus.Info("Extracting data from plain text")
+	body, encoding := decodeBody(body, resp.Header.Get("Content-Type"))
+	text := string(body)
+
+	result := &types.AnalyzeResultes{
+		Headings: make(map[string]int),
+		Encoding: encoding,
+		Document: &types.DocumentInfo{Format: "Plain text", Words: len(strings.Fields(text))},
+	}
+	for _, line := range strings.Split(text, "\n") {
+		result.Document.Lines++
+		if result.Title == "" {
+			result.Title = strings.TrimSpace(line)
+		}
+	}
+
+	if opts.enabled("links") {
+		checkLinks(result, linksFromURLs(textURLRegex.FindAllString(text, -1), pageURL), opts)
+	}
+	return result, nil
+}
+
+// linksFromURLs turns absolute URLs found in a document into link results
+func linksFromURLs(urls []string, pageURL *url.URL) []types.LinkResult {
+	links := []types.LinkResult{}
+	for _, raw := range urls {
+		href := strings.TrimRight(raw, ".,;:!?")
+		parsed, err := url.Parse(href)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		link := types.LinkResult{Href: href, Type: types.LinkTypeExternal, Status: types.LinkStatusUnchecked}
+		if parsed.Host == pageURL.Host {
+			link.Type = types.LinkTypeInternal
+		}
+		links = append(links, link)
+	}
+	return links
+}
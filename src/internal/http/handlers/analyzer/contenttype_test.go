@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_detectMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "Declared HTML", contentType: "text/html; charset=utf-8", body: "<p>hi</p>", want: "text/html"},
+		{name: "Declared XHTML", contentType: "application/xhtml+xml", body: `<?xml version="1.0"?><html></html>`, want: "application/xhtml+xml"},
+		{name: "Missing header", body: "<!DOCTYPE html><html></html>", want: "text/html"},
+		{name: "Generic binary", contentType: "application/octet-stream", body: "%PDF-1.7\n", want: "application/pdf"},
+		{name: "PDF labeled as text", contentType: "text/plain", body: "%PDF-1.4\n", want: "application/pdf"},
+		{name: "Plain text", contentType: "text/plain; charset=utf-8", body: "hello", want: "text/plain"},
+		{name: "Image", contentType: "image/png", body: "\x89PNG\r\n\x1a\n", want: "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectMediaType(tt.contentType, []byte(tt.body)))
+		})
+	}
+}
+
+func serveContent(contentType string, body []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+}
+
+func Test_analyzePage_XHTML(t *testing.T) {
+	ts := serveContent("application/xhtml+xml", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>XHTML page</title></head><body><h1>Heading</h1><br/></body></html>`))
+	defer ts.Close()
+
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/xhtml+xml", result.ContentType)
+	assert.Equal(t, "XHTML page", result.Title)
+	assert.Equal(t, "XHTML", result.HTMLVersion)
+	assert.Equal(t, 1, result.Headings["h1"])
+}
+
+func Test_analyzePage_PlainText(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("\n  Release notes\n\nSee http://" + r.Host + "/changelog. Thanks!\n"))
+	}))
+	defer ts.Close()
+
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", result.ContentType)
+	assert.Equal(t, "Release notes", result.Title)
+	assert.Equal(t, types.DocumentInfo{Format: "Plain text", Lines: 5, Words: 5}, *result.Document)
+	if assert.Len(t, result.Links, 1) {
+		assert.Equal(t, ts.URL+"/changelog", result.Links[0].Href)
+		assert.Equal(t, types.LinkTypeInternal, result.Links[0].Type)
+	}
+	assert.Equal(t, 1, result.InternalLinks)
+	assert.NotNil(t, result.SecurityHeaders)
+}
+
+func Test_analyzePage_UnsupportedContentType(t *testing.T) {
+	ts := serveContent("image/png", []byte("\x89PNG\r\n\x1a\n"))
+	defer ts.Close()
+
+	_, err := analyzePage(ts.URL)
+	var contentTypeErr *UnsupportedContentTypeError
+	if assert.ErrorAs(t, err, &contentTypeErr) {
+		assert.Equal(t, "image/png", contentTypeErr.ContentType)
+	}
+
+	body, _ := json.Marshal(types.RequestPayload{URL: ts.URL})
+	rec := httptest.NewRecorder()
+	GetResults(rec, httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Cookie lifetime thresholds
+const (
+	maxCookieLifetime  = 400 * 24 * time.Hour
+	maxSessionLifetime = 30 * 24 * time.Hour
+)
+
+var (
+	sessionCookieRegex = regexp.MustCompile(`(?i)(sess|sid$|^sid|auth|token|jwt|login|remember|csrf|xsrf)`)
+	// csrfCookieRegex matches double-submit CSRF cookies, which scripts have to read
+	csrfCookieRegex = regexp.MustCompile(`(?i)(csrf|xsrf)`)
+)
+
+// auditCookies parses the Set-Cookie headers of the response and of every
+// redirect that led to it
+func auditCookies(resp *http.Response) *types.CookieReport {
+	logrus.Debug("Auditing cookies")
+	report := &types.CookieReport{Cookies: []types.CookieInfo{}, Findings: []types.Finding{}}
+
+	for _, r := range redirectChain(resp) {
+		now := time.Now()
+		if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+			now = date
+		}
+		for _, line := range r.Header.Values("Set-Cookie") {
+			cookie, err := http.ParseSetCookie(line)
+			if err != nil {
+				addFinding(&report.Findings, types.SeverityWarning, "cookie-invalid", "Set-Cookie header from %s could not be parsed: %v", r.Request.URL, err)
+				continue
+			}
+			info := describeCookie(cookie, r.Request.URL.String(), now)
+			report.Cookies = append(report.Cookies, info)
+			checkCookie(info, r.Request.URL.Hostname(), &report.Findings)
+		}
+	}
+
+	logrus.Debug("Audited ", len(report.Cookies), " cookies")
+	return report
+}
+
+// redirectChain returns the responses that led to resp, oldest first, ending with resp
+func redirectChain(resp *http.Response) []*http.Response {
+	var chain []*http.Response
+	for r := resp; r != nil; {
+		chain = append([]*http.Response{r}, chain...)
+		if r.Request == nil {
+			break
+		}
+		r = r.Request.Response
+	}
+	return chain
+}
+
+func describeCookie(cookie *http.Cookie, setBy string, now time.Time) types.CookieInfo {
+	info := types.CookieInfo{
+		Name:     cookie.Name,
+		SetBy:    setBy,
+		Domain:   strings.TrimPrefix(strings.ToLower(cookie.Domain), "."),
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: sameSiteName(cookie.SameSite),
+	}
+
+	// Max-Age takes precedence over Expires
+	switch {
+	case cookie.MaxAge > 0:
+		info.LifetimeSeconds = int64(cookie.MaxAge)
+		info.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).UTC().Format(time.RFC3339)
+	case cookie.MaxAge < 0:
+		info.LifetimeSeconds = -1
+	case !cookie.Expires.IsZero():
+		info.LifetimeSeconds = int64(cookie.Expires.Sub(now).Seconds())
+		if info.LifetimeSeconds == 0 {
+			info.LifetimeSeconds = -1
+		}
+		info.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+	}
+	return info
+}
+
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+func checkCookie(cookie types.CookieInfo, host string, findings *[]types.Finding) {
+	if cookie.LifetimeSeconds < 0 {
+		// Deleting a cookie carries no risk
+		return
+	}
+
+	sessionLike := sessionCookieRegex.MatchString(cookie.Name)
+	if sessionLike && !cookie.Secure {
+		addFinding(findings, types.SeverityError, "cookie-secure", "Session cookie %q is missing the Secure attribute", cookie.Name)
+	}
+	if sessionLike && !cookie.HttpOnly && !csrfCookieRegex.MatchString(cookie.Name) {
+		addFinding(findings, types.SeverityWarning, "cookie-httponly", "Session cookie %q is readable from JavaScript (missing HttpOnly)", cookie.Name)
+	}
+
+	switch {
+	case cookie.SameSite == "None" && !cookie.Secure:
+		addFinding(findings, types.SeverityError, "cookie-samesite", "Cookie %q sets SameSite=None without Secure and will be rejected", cookie.Name)
+	case cookie.SameSite == "" && sessionLike:
+		addFinding(findings, types.SeverityInfo, "cookie-samesite", "Session cookie %q has no SameSite attribute and relies on the browser default", cookie.Name)
+	}
+
+	if strings.HasPrefix(cookie.Name, "__Secure-") && !cookie.Secure {
+		addFinding(findings, types.SeverityError, "cookie-prefix", "Cookie %q uses the __Secure- prefix without Secure", cookie.Name)
+	}
+	if strings.HasPrefix(cookie.Name, "__Host-") && (!cookie.Secure || cookie.Domain != "" || cookie.Path != "/") {
+		addFinding(findings, types.SeverityError, "cookie-prefix", "Cookie %q uses the __Host- prefix but needs Secure, Path=/ and no Domain", cookie.Name)
+	}
+
+	if cookie.Domain != "" {
+		if suffix, _ := publicsuffix.PublicSuffix(cookie.Domain); suffix == cookie.Domain {
+			addFinding(findings, types.SeverityError, "cookie-domain", "Cookie %q is scoped to the public suffix %q", cookie.Name, cookie.Domain)
+		} else if !strings.EqualFold(cookie.Domain, host) {
+			addFinding(findings, types.SeverityInfo, "cookie-domain", "Cookie %q is shared with every subdomain of %s", cookie.Name, cookie.Domain)
+		}
+	}
+
+	lifetime := time.Duration(cookie.LifetimeSeconds) * time.Second
+	switch {
+	case lifetime > maxCookieLifetime:
+		addFinding(findings, types.SeverityWarning, "cookie-lifetime", "Cookie %q lives for %d days, browsers cap lifetimes at 400 days", cookie.Name, int(lifetime.Hours()/24))
+	case sessionLike && lifetime > maxSessionLifetime:
+		addFinding(findings, types.SeverityInfo, "cookie-lifetime", "Session cookie %q persists for %d days", cookie.Name, int(lifetime.Hours()/24))
+	}
+}
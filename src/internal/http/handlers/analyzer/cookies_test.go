@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_checkCookie(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   types.CookieInfo
+		host     string
+		severity string
+		check    string
+	}{
+		{name: "Session without Secure", cookie: types.CookieInfo{Name: "PHPSESSID", HttpOnly: true, SameSite: "Lax"}, severity: types.SeverityError, check: "cookie-secure"},
+		{name: "Session without HttpOnly", cookie: types.CookieInfo{Name: "auth_token", Secure: true, SameSite: "Lax"}, severity: types.SeverityWarning, check: "cookie-httponly"},
+		{name: "SameSite None without Secure", cookie: types.CookieInfo{Name: "pref", SameSite: "None"}, severity: types.SeverityError, check: "cookie-samesite"},
+		{name: "Host prefix with domain", cookie: types.CookieInfo{Name: "__Host-id", Secure: true, Path: "/", Domain: "example.com"}, host: "www.example.com", severity: types.SeverityError, check: "cookie-prefix"},
+		{name: "Public suffix domain", cookie: types.CookieInfo{Name: "pref", Domain: "co.uk"}, host: "shop.example.co.uk", severity: types.SeverityError, check: "cookie-domain"},
+		{name: "Parent domain", cookie: types.CookieInfo{Name: "pref", Domain: "example.com"}, host: "www.example.com", severity: types.SeverityInfo, check: "cookie-domain"},
+		{name: "Excessive lifetime", cookie: types.CookieInfo{Name: "pref", LifetimeSeconds: 500 * 24 * 3600}, severity: types.SeverityWarning, check: "cookie-lifetime"},
+		{name: "Long-lived session", cookie: types.CookieInfo{Name: "remember_me", Secure: true, HttpOnly: true, SameSite: "Lax", LifetimeSeconds: 90 * 24 * 3600}, severity: types.SeverityInfo, check: "cookie-lifetime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var findings []types.Finding
+			checkCookie(tt.cookie, tt.host, &findings)
+			assert.True(t, hasFinding(findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, findings)
+		})
+	}
+}
+
+func Test_checkCookie_CSRFToken(t *testing.T) {
+	// Double-submit CSRF cookies are read by scripts, so only Secure and SameSite apply
+	var findings []types.Finding
+	checkCookie(types.CookieInfo{Name: "XSRF-TOKEN"}, "example.com", &findings)
+
+	assert.False(t, hasFinding(findings, types.SeverityWarning, "cookie-httponly"), findings)
+	assert.True(t, hasFinding(findings, types.SeverityError, "cookie-secure"), findings)
+	assert.True(t, hasFinding(findings, types.SeverityInfo, "cookie-samesite"), findings)
+}
+
+func Test_auditCookies_AcrossRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "sessionid=abc; Path=/")
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "theme=dark; Max-Age=3600; Secure; HttpOnly; SameSite=Strict")
+		w.Header().Add("Set-Cookie", "old=; Max-Age=0")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/start")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	got := auditCookies(resp)
+
+	assert.Len(t, got.Cookies, 3)
+	assert.Equal(t, "sessionid", got.Cookies[0].Name)
+	assert.Equal(t, ts.URL+"/start", got.Cookies[0].SetBy)
+	assert.Equal(t, types.CookieInfo{Name: "theme", SetBy: ts.URL + "/page", Secure: true, HttpOnly: true, SameSite: "Strict", Expires: got.Cookies[1].Expires, LifetimeSeconds: 3600}, got.Cookies[1])
+	assert.Equal(t, int64(-1), got.Cookies[2].LifetimeSeconds)
+	assert.True(t, hasFinding(got.Findings, types.SeverityError, "cookie-secure"))
+}
@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/config"
+)
+
+// Credential types
+const (
+	CredentialBasic      = "basic"
+	CredentialBearer     = "bearer"
+	CredentialHeader     = "header"
+	CredentialClientCert = "client_cert"
+)
+
+// credential is a config.Credential with its secrets resolved. Header
+// credentials, including basic and bearer, are reduced to a header name and value.
+type credential struct {
+	hosts       []credentialHost
+	kind        string
+	header      string
+	value       string
+	certificate *tls.Certificate
+	allowHTTP   bool
+}
+
+// credentialHost is a host pattern, restricted to port when one is given
+type credentialHost struct {
+	pattern string
+	port    string
+}
+
+var (
+	credentialsMu sync.RWMutex
+	credentials   []*credential
+)
+
+// ConfigureCredentials resolves the secrets of the configured credentials and
+// loads their client certificates. Secrets are never logged.
+func ConfigureCredentials(cfgs []config.Credential) error {
+	resolved := make([]*credential, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		cred, err := resolveCredential(cfg)
+		if err != nil {
+			return fmt.Errorf("credential %d (%s): %w", i+1, cfg.Type, err)
+		}
+		resolved = append(resolved, cred)
+		logrus.WithFields(logrus.Fields{"type": cred.kind, "hosts": cfg.Hosts}).Info("Credential configured")
+	}
+
+	credentialsMu.Lock()
+	credentials = resolved
+	credentialsMu.Unlock()
+	httpClient.CloseIdleConnections()
+	return nil
+}
+
+func resolveCredential(cfg config.Credential) (*credential, error) {
+	cred := &credential{kind: cfg.Type, allowHTTP: cfg.AllowHTTP}
+	for _, entry := range cfg.Hosts {
+		host, port := strings.TrimSpace(entry), ""
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+				return nil, fmt.Errorf("invalid port in host %q", entry)
+			}
+			host, port = h, p
+		}
+		for _, pattern := range normalizeHostPatterns([]string{strings.Trim(host, "[]")}) {
+			cred.hosts = append(cred.hosts, credentialHost{pattern: pattern, port: port})
+		}
+	}
+	if len(cred.hosts) == 0 {
+		return nil, fmt.Errorf("no hosts are listed")
+	}
+
+	switch cfg.Type {
+	case CredentialBasic:
+		password, err := resolveSecret(cfg.Password)
+		if err != nil {
+			return nil, fmt.Errorf("password: %w", err)
+		}
+		if cfg.Username == "" {
+			return nil, fmt.Errorf("username is required")
+		}
+		cred.header = "Authorization"
+		cred.value = "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+password))
+	case CredentialBearer:
+		token, err := resolveSecret(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("token: %w", err)
+		}
+		cred.header, cred.value = "Authorization", "Bearer "+token
+	case CredentialHeader:
+		value, err := resolveSecret(cfg.HeaderValue)
+		if err != nil {
+			return nil, fmt.Errorf("header_value: %w", err)
+		}
+		if cfg.HeaderName == "" || restrictedHeaders[strings.ToLower(cfg.HeaderName)] {
+			return nil, fmt.Errorf("invalid header_name %q", cfg.HeaderName)
+		}
+		cred.header, cred.value = http.CanonicalHeaderKey(cfg.HeaderName), value
+	case CredentialClientCert:
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading the client certificate: %w", err)
+		}
+		cred.certificate = &certificate
+	default:
+		return nil, fmt.Errorf("unknown credential type %q", cfg.Type)
+	}
+	return cred, nil
+}
+
+// resolveSecret reads the secret a reference points to. Inline secrets are
+// refused so that they stay out of the config file.
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("reading secret file: %w", err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		if value == "" {
+			return "", fmt.Errorf("secret file %s is empty", strings.TrimPrefix(ref, "file:"))
+		}
+		return value, nil
+	case ref == "":
+		return "", fmt.Errorf("a secret reference is required")
+	}
+	return "", fmt.Errorf(`secrets must be referenced as "env:NAME" or "file:/path"`)
+}
+
+// credentialFor returns the credential of creds configured for the host and
+// port of u, if any. Header credentials are never sent in cleartext unless
+// the credential allows http.
+func credentialFor(creds []*credential, u *url.URL) *credential {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[strings.ToLower(u.Scheme)]
+	}
+	for _, cred := range creds {
+		if cred.header != "" && !strings.EqualFold(u.Scheme, "https") && !cred.allowHTTP {
+			continue
+		}
+		for _, h := range cred.hosts {
+			if (h.port == "" || h.port == port) && matchesHostPattern(host, []string{h.pattern}) {
+				return cred
+			}
+		}
+	}
+	return nil
+}
+
+// credentialTransport adds the credential of the request's host to every
+// request, including each hop of a redirect, and removes credential headers
+// carried over from another host
+type credentialTransport struct {
+	base *http.Transport
+
+	mu             sync.Mutex
+	certTransports map[*credential]*http.Transport
+}
+
+func newCredentialTransport(base *http.Transport) *credentialTransport {
+	return &credentialTransport{base: base, certTransports: make(map[*credential]*http.Transport)}
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	credentialsMu.RLock()
+	creds := credentials
+	credentialsMu.RUnlock()
+	if len(creds) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	cred := credentialFor(creds, req.URL)
+	req = req.Clone(req.Context())
+	for _, other := range creds {
+		if other != cred && other.header != "" && req.Header.Get(other.header) == other.value {
+			req.Header.Del(other.header)
+		}
+	}
+
+	switch {
+	case cred == nil:
+		return t.base.RoundTrip(req)
+	case cred.certificate != nil:
+		return t.certTransport(cred).RoundTrip(req)
+	}
+	req.Header.Set(cred.header, cred.value)
+	return t.base.RoundTrip(req)
+}
+
+// certTransport returns a transport presenting the client certificate of cred;
+// connections are pooled per transport, so they never mix certificates
+func (t *credentialTransport) certTransport(cred *credential) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.certTransports[cred]; ok {
+		return transport
+	}
+	transport := t.base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{*cred.certificate}
+	t.certTransports[cred] = transport
+	return transport
+}
+
+func (t *credentialTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for cred, transport := range t.certTransports {
+		transport.CloseIdleConnections()
+		delete(t.certTransports, cred)
+	}
+}
@@ -0,0 +1,313 @@
+package analyzer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// withCredentials replaces the configured credentials for the duration of the test
+func withCredentials(t *testing.T, cfgs []config.Credential) {
+	credentialsMu.RLock()
+	previous := credentials
+	credentialsMu.RUnlock()
+	assert.NoError(t, ConfigureCredentials(cfgs))
+	t.Cleanup(func() {
+		credentialsMu.Lock()
+		credentials = previous
+		credentialsMu.Unlock()
+		httpClient.CloseIdleConnections()
+	})
+}
+
+func Test_resolveSecret(t *testing.T) {
+	t.Setenv("ANALYZER_TEST_SECRET", "from-env")
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "Environment variable", ref: "env:ANALYZER_TEST_SECRET", want: "from-env"},
+		{name: "File", ref: "file:" + secretFile, want: "from-file"},
+		{name: "Unset environment variable", ref: "env:ANALYZER_TEST_UNSET", wantErr: "is not set"},
+		{name: "Missing file", ref: "file:" + filepath.Join(dir, "missing"), wantErr: "reading secret file"},
+		{name: "Empty file", ref: "file:" + emptyFile, wantErr: "is empty"},
+		{name: "Inline secret", ref: "hunter2", wantErr: "must be referenced"},
+		{name: "Missing reference", ref: "", wantErr: "is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(tt.ref)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.NotContains(t, err.Error(), "hunter2")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ConfigureCredentials_Invalid(t *testing.T) {
+	withCredentials(t, nil)
+	t.Setenv("ANALYZER_TEST_SECRET", "secret")
+
+	tests := []struct {
+		name    string
+		cfg     config.Credential
+		wantErr string
+	}{
+		{name: "No hosts", cfg: config.Credential{Type: CredentialBearer, Token: "env:ANALYZER_TEST_SECRET"}, wantErr: "no hosts"},
+		{name: "Unknown type", cfg: config.Credential{Hosts: []string{"example.com"}, Type: "digest"}, wantErr: "unknown credential type"},
+		{name: "Basic without username", cfg: config.Credential{Hosts: []string{"example.com"}, Type: CredentialBasic, Password: "env:ANALYZER_TEST_SECRET"}, wantErr: "username is required"},
+		{name: "Inline password", cfg: config.Credential{Hosts: []string{"example.com"}, Type: CredentialBasic, Username: "u", Password: "secret"}, wantErr: "password"},
+		{name: "Restricted header", cfg: config.Credential{Hosts: []string{"example.com"}, Type: CredentialHeader, HeaderName: "Host", HeaderValue: "env:ANALYZER_TEST_SECRET"}, wantErr: "invalid header_name"},
+		{name: "Invalid port", cfg: config.Credential{Hosts: []string{"example.com:http"}, Type: CredentialBearer, Token: "env:ANALYZER_TEST_SECRET"}, wantErr: "invalid port"},
+		{name: "Missing certificate", cfg: config.Credential{Hosts: []string{"example.com"}, Type: CredentialClientCert, CertFile: "missing.crt", KeyFile: "missing.key"}, wantErr: "client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfigureCredentials([]config.Credential{tt.cfg})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_GetResults_Credentials(t *testing.T) {
+	const password, apiKey = "s3cret-password", "s3cret-key"
+	t.Setenv("ANALYZER_TEST_PASSWORD", password)
+	t.Setenv("ANALYZER_TEST_API_KEY", apiKey)
+
+	var otherRequests []*http.Request
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherRequests = append(otherRequests, r)
+	}))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	otherURL.Host = net.JoinHostPort("localhost", otherURL.Port())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "analyzer" || pass != password || r.Header.Get("X-Api-Key") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/away" {
+			http.Redirect(w, r, otherURL.String()+"/landing", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`<html><head><title>Members</title></head><body>
+			<a href="` + otherURL.String() + `/partner">Partner</a><a href="/away">Away</a></body></html>`))
+	}))
+	defer ts.Close()
+
+	hook := test.NewLocal(logrus.StandardLogger())
+	defer hook.Reset()
+	withCredentials(t, []config.Credential{
+		{Hosts: []string{strings.TrimPrefix(ts.URL, "http://")}, Type: CredentialBasic, Username: "analyzer", Password: "env:ANALYZER_TEST_PASSWORD", AllowHTTP: true},
+		{Hosts: []string{"*.partner.example"}, Type: CredentialHeader, HeaderName: "x-api-key", HeaderValue: "env:ANALYZER_TEST_API_KEY"},
+	})
+
+	body, _ := json.Marshal(types.RequestPayload{URL: ts.URL, CheckInternalLinks: true})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	GetResults(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Members")
+	// Neither the link check nor the redirect from /away carries the credential
+	if assert.Len(t, otherRequests, 2) {
+		for _, r := range otherRequests {
+			assert.Empty(t, r.Header.Get("Authorization"))
+		}
+	}
+	assert.NotContains(t, rec.Body.String(), password)
+	for _, entry := range hook.AllEntries() {
+		line, _ := entry.String()
+		assert.NotContains(t, line, password)
+		assert.NotContains(t, line, apiKey)
+	}
+}
+
+func Test_credentialFor(t *testing.T) {
+	bearer := &credential{hosts: []credentialHost{{pattern: "intranet.example.com"}}, kind: CredentialBearer, header: "Authorization", value: "Bearer t"}
+	ported := &credential{hosts: []credentialHost{{pattern: ".staging.example.com", port: "8443"}}, kind: CredentialHeader, header: "X-Api-Key", value: "k"}
+	cleartext := &credential{hosts: []credentialHost{{pattern: "legacy.example.com"}}, kind: CredentialBasic, header: "Authorization", value: "Basic x", allowHTTP: true}
+	creds := []*credential{bearer, ported, cleartext}
+
+	tests := []struct {
+		name string
+		url  string
+		want *credential
+	}{
+		{name: "Https", url: "https://intranet.example.com/", want: bearer},
+		{name: "Http is refused", url: "http://intranet.example.com/"},
+		{name: "Http allowed by the credential", url: "http://legacy.example.com/", want: cleartext},
+		{name: "Matching port", url: "https://app.staging.example.com:8443/", want: ported},
+		{name: "Default port does not match", url: "https://app.staging.example.com/"},
+		{name: "Other port", url: "https://app.staging.example.com:9443/"},
+		{name: "Other host", url: "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			assert.Same(t, tt.want, credentialFor(creds, u))
+		})
+	}
+}
+
+func Test_credentialTransport_StripsForeignCredentials(t *testing.T) {
+	t.Setenv("ANALYZER_TEST_API_KEY", "s3cret-key")
+	var received http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer ts.Close()
+	withCredentials(t, []config.Credential{
+		{Hosts: []string{"partner.example"}, Type: CredentialHeader, HeaderName: "X-Api-Key", HeaderValue: "env:ANALYZER_TEST_API_KEY"},
+	})
+
+	// A redirect copies the headers of the first hop, including the credential
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("X-Api-Key", "s3cret-key")
+	req.Header.Set("X-Other", "kept")
+	resp, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Empty(t, received.Get("X-Api-Key"))
+	assert.Equal(t, "kept", received.Get("X-Other"))
+	assert.Equal(t, "s3cret-key", req.Header.Get("X-Api-Key"), "the caller's request is not modified")
+}
+
+func Test_fetchURL_ClientCertificate(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("<html><head><title>mTLS</title></head></html>"))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+	withTrustedServer(t, ts)
+
+	withCredentials(t, nil)
+	_, err := fetchURL(ts.URL)
+	assert.Error(t, err, "the server requires a client certificate")
+
+	certFile, keyFile := writeClientCertificate(t)
+	withCredentials(t, []config.Credential{{Hosts: []string{"127.0.0.1"}, Type: CredentialClientCert, CertFile: certFile, KeyFile: keyFile}})
+	resp, err := fetchURL(ts.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+}
+
+func Test_GetResults_CookieJar(t *testing.T) {
+	var aboutCookies []*http.Cookie
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "seeded" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "visited", Value: "yes"})
+			w.Write([]byte(`<html><head><title>Session</title></head><body><a href="/about">About</a></body></html>`))
+		case "/about":
+			aboutCookies = r.Cookies()
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		payload types.RequestPayload
+		status  int
+		cookies int
+	}{
+		{name: "Seeded jar", payload: types.RequestPayload{Cookies: map[string]string{"session": "seeded"}, CheckInternalLinks: true}, status: http.StatusOK, cookies: 2},
+		{name: "No jar", payload: types.RequestPayload{CheckInternalLinks: true}, status: http.StatusUnauthorized},
+		{name: "Invalid cookie", payload: types.RequestPayload{Cookies: map[string]string{"bad name": "x"}}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aboutCookies = nil
+			tt.payload.URL = ts.URL
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			GetResults(rec, req)
+
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, http.StatusInternalServerError, rec.Code)
+				assert.Contains(t, rec.Body.String(), "401")
+				return
+			}
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Len(t, aboutCookies, tt.cookies)
+			assert.NotContains(t, rec.Body.String(), "seeded")
+		})
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// to the test's temporary directory
+func writeClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "analyzer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
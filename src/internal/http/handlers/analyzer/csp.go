@@ -0,0 +1,448 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// minNonceLength is the shortest nonce, in base64 characters, that carries 96 bits
+// of entropy
+const minNonceLength = 16
+
+// Directives that are ignored when a policy is delivered through <meta>
+var metaIgnoredDirectives = []string{"frame-ancestors", "report-uri", "sandbox"}
+
+// Script types that are data blocks and never executed
+var scriptDataTypes = toSet("application/ld+json", "application/json", "text/template", "text/x-template", "importmap", "speculationrules")
+
+// parseCSP splits a header or meta value into policies. Multiple policies in one
+// header are separated by commas.
+func parseCSP(value, source string) []types.CSPPolicy {
+	var policies []types.CSPPolicy
+	for _, serialized := range strings.Split(value, ",") {
+		policy := types.CSPPolicy{
+			Source:     source,
+			ReportOnly: source == types.CSPSourceHeaderReportOnly,
+			Directives: make(map[string][]string),
+		}
+		for _, directive := range strings.Split(serialized, ";") {
+			tokens := strings.Fields(directive)
+			if len(tokens) == 0 {
+				continue
+			}
+			name := strings.ToLower(tokens[0])
+			// Only the first occurrence of a directive is honoured
+			if _, exists := policy.Directives[name]; exists {
+				continue
+			}
+			policy.Directives[name] = tokens[1:]
+		}
+		if len(policy.Directives) > 0 {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// collectCSP gathers every policy delivered through headers and <meta http-equiv>
+func collectCSP(doc *goquery.Document, header http.Header) []types.CSPPolicy {
+	var policies []types.CSPPolicy
+	for _, value := range header.Values("Content-Security-Policy") {
+		policies = append(policies, parseCSP(value, types.CSPSourceHeader)...)
+	}
+	for _, value := range header.Values("Content-Security-Policy-Report-Only") {
+		policies = append(policies, parseCSP(value, types.CSPSourceHeaderReportOnly)...)
+	}
+	if doc != nil {
+		doc.Find("meta[http-equiv]").Each(func(i int, s *goquery.Selection) {
+			if strings.EqualFold(strings.TrimSpace(s.AttrOr("http-equiv", "")), "Content-Security-Policy") {
+				policies = append(policies, parseCSP(s.AttrOr("content", ""), types.CSPSourceMeta)...)
+			}
+		})
+	}
+	return policies
+}
+
+// evaluateCSP flags weaknesses in the page's policies and reports which of the
+// page's own scripts the enforced policies would block
+func evaluateCSP(doc *goquery.Document, header http.Header, pageURL *url.URL) *types.CSPReport {
+	logrus.Debug("Evaluating Content-Security-Policy")
+	report := &types.CSPReport{Policies: collectCSP(doc, header), Findings: []types.Finding{}}
+	if len(report.Policies) == 0 {
+		return report
+	}
+
+	var enforced []types.CSPPolicy
+	for _, policy := range report.Policies {
+		checkPolicyWeaknesses(policy, &report.Findings)
+		if !policy.ReportOnly {
+			enforced = append(enforced, policy)
+		}
+	}
+	if len(enforced) == 0 {
+		addFinding(&report.Findings, types.SeverityWarning, "csp-report-only", "All policies are report-only, nothing is enforced")
+		return report
+	}
+
+	checkPageScripts(doc, enforced, pageURL, report)
+	logrus.Debug("CSP evaluated with ", len(report.Findings), " findings")
+	return report
+}
+
+// scriptSources returns the source list governing scripts, falling back to default-src
+func scriptSources(policy types.CSPPolicy) ([]string, bool) {
+	if sources, ok := policy.Directives["script-src"]; ok {
+		return sources, true
+	}
+	sources, ok := policy.Directives["default-src"]
+	return sources, ok
+}
+
+func checkPolicyWeaknesses(policy types.CSPPolicy, findings *[]types.Finding) {
+	label := policy.Source
+	if policy.Source == types.CSPSourceMeta {
+		for _, name := range metaIgnoredDirectives {
+			if _, ok := policy.Directives[name]; ok {
+				addFinding(findings, types.SeverityWarning, "csp-meta", "%s policy: %s is ignored when delivered through <meta>", label, name)
+			}
+		}
+	}
+
+	scripts, restricted := scriptSources(policy)
+	if !restricted {
+		addFinding(findings, types.SeverityError, "csp-script-src", "%s policy: neither script-src nor default-src is set, scripts are unrestricted", label)
+	}
+
+	hasNonceOrHash := false
+	for _, source := range scripts {
+		if isNonceSource(source) || isHashSource(source) {
+			hasNonceOrHash = true
+		}
+	}
+	for _, source := range scripts {
+		lower := strings.ToLower(source)
+		switch {
+		case lower == "'unsafe-inline'" && !hasNonceOrHash:
+			addFinding(findings, types.SeverityError, "csp-unsafe-inline", "%s policy: script sources allow 'unsafe-inline'", label)
+		case lower == "'unsafe-eval'":
+			addFinding(findings, types.SeverityWarning, "csp-unsafe-eval", "%s policy: script sources allow 'unsafe-eval'", label)
+		case lower == "*" || lower == "http:" || lower == "https:" || lower == "data:" || lower == "blob:":
+			addFinding(findings, types.SeverityError, "csp-wildcard", "%s policy: script sources allow %s", label, source)
+		case lower == "'strict-dynamic'" && !hasNonceOrHash:
+			addFinding(findings, types.SeverityWarning, "csp-strict-dynamic", "%s policy: 'strict-dynamic' without a nonce or hash blocks all scripts", label)
+		case isNonceSource(source):
+			if nonce := strings.TrimSuffix(strings.TrimPrefix(source, "'nonce-"), "'"); len(nonce) < minNonceLength {
+				addFinding(findings, types.SeverityWarning, "csp-nonce", "%s policy: nonce %q is too short to be unguessable", label, nonce)
+			}
+		case isHashSource(source) && !validHashSource(source):
+			addFinding(findings, types.SeverityError, "csp-hash", "%s policy: hash source %s is malformed", label, source)
+		}
+	}
+
+	// Map order is random, sort so the findings come out the same every time
+	names := make([]string, 0, len(policy.Directives))
+	for name := range policy.Directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, source := range policy.Directives[name] {
+			lower := strings.ToLower(source)
+			if strings.HasPrefix(lower, "http://") || (lower == "http:" && name != "script-src") {
+				addFinding(findings, types.SeverityWarning, "csp-http-source", "%s policy: %s allows insecure source %s", label, name, source)
+			}
+			if lower == "*" && name != "script-src" && !(name == "default-src" && policy.Directives["script-src"] == nil) {
+				addFinding(findings, types.SeverityWarning, "csp-wildcard", "%s policy: %s allows any origin", label, name)
+			}
+		}
+	}
+
+	if objects, ok := policy.Directives["object-src"]; !ok {
+		if defaults, ok := policy.Directives["default-src"]; !ok || !isNoneSource(defaults) {
+			addFinding(findings, types.SeverityWarning, "csp-object-src", "%s policy: object-src is missing, set it to 'none'", label)
+		}
+	} else if !isNoneSource(objects) {
+		addFinding(findings, types.SeverityInfo, "csp-object-src", "%s policy: object-src allows plugins, 'none' is recommended", label)
+	}
+	if _, ok := policy.Directives["base-uri"]; !ok {
+		addFinding(findings, types.SeverityWarning, "csp-base-uri", "%s policy: base-uri is missing, injected <base> tags can redirect scripts", label)
+	}
+}
+
+func isNoneSource(sources []string) bool {
+	return len(sources) == 1 && strings.EqualFold(sources[0], "'none'")
+}
+
+func isNonceSource(source string) bool {
+	return strings.HasPrefix(strings.ToLower(source), "'nonce-") && strings.HasSuffix(source, "'")
+}
+
+// nonceMatches reports whether source is a nonce source for nonce. The keyword
+// is case-insensitive but the value is compared exactly, as nonces are base64.
+func nonceMatches(source, nonce string) bool {
+	return nonce != "" && isNonceSource(source) && source[len("'nonce-"):len(source)-1] == nonce
+}
+
+func isHashSource(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "'sha256-") || strings.HasPrefix(lower, "'sha384-") || strings.HasPrefix(lower, "'sha512-")
+}
+
+// validHashSource checks that the digest decodes to the length of its algorithm
+func validHashSource(source string) bool {
+	algorithm, digest, _ := strings.Cut(strings.Trim(source, "'"), "-")
+	decoded, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return len(decoded) == sha256.Size
+	case "sha384":
+		return len(decoded) == sha512.Size384
+	case "sha512":
+		return len(decoded) == sha512.Size
+	}
+	return false
+}
+
+// checkPageScripts reports inline scripts, event handlers and script origins
+// that at least one enforced policy would block
+func checkPageScripts(doc *goquery.Document, enforced []types.CSPPolicy, pageURL *url.URL, report *types.CSPReport) {
+	blockedOrigins := make(map[string]bool)
+
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		scriptType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		if scriptDataTypes[scriptType] {
+			return
+		}
+		nonce := s.AttrOr("nonce", "")
+
+		src, external := s.Attr("src")
+		if !external {
+			for _, policy := range enforced {
+				if !inlineScriptAllowed(policy, s.Text(), nonce) {
+					report.BlockedInlineScripts++
+					addFinding(&report.Findings, types.SeverityWarning, "csp-blocked-inline", "Inline script would be blocked by the %s policy", policy.Source)
+					report.Findings[len(report.Findings)-1].Selector = cssPath(s)
+					break
+				}
+			}
+			return
+		}
+
+		ref, err := url.Parse(strings.TrimSpace(src))
+		if err != nil {
+			return
+		}
+		scriptURL := pageURL.ResolveReference(ref)
+		for _, policy := range enforced {
+			if !externalScriptAllowed(policy, scriptURL, pageURL, nonce) {
+				blockedOrigins[scriptURL.Scheme+"://"+scriptURL.Host] = true
+				addFinding(&report.Findings, types.SeverityWarning, "csp-blocked-script", "Script %s would be blocked by the %s policy", scriptURL, policy.Source)
+				report.Findings[len(report.Findings)-1].Selector = cssPath(s)
+				break
+			}
+		}
+	})
+
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		for _, attr := range s.Get(0).Attr {
+			if !strings.HasPrefix(strings.ToLower(attr.Key), "on") {
+				continue
+			}
+			for _, policy := range enforced {
+				if !eventHandlerAllowed(policy) {
+					report.BlockedEventHandlers++
+					break
+				}
+			}
+		}
+	})
+	if report.BlockedEventHandlers > 0 {
+		addFinding(&report.Findings, types.SeverityWarning, "csp-blocked-handler", "%d inline event handler attributes would be blocked", report.BlockedEventHandlers)
+	}
+
+	for origin := range blockedOrigins {
+		report.BlockedScriptOrigins = append(report.BlockedScriptOrigins, origin)
+	}
+	sort.Strings(report.BlockedScriptOrigins)
+}
+
+func inlineScriptAllowed(policy types.CSPPolicy, content, nonce string) bool {
+	sources, ok := scriptSources(policy)
+	if !ok {
+		return true
+	}
+
+	hasNonceOrHash := false
+	for _, source := range sources {
+		switch {
+		case isNonceSource(source):
+			hasNonceOrHash = true
+			if nonceMatches(source, nonce) {
+				return true
+			}
+		case isHashSource(source):
+			hasNonceOrHash = true
+			if hashMatches(source, content) {
+				return true
+			}
+		}
+	}
+	// 'unsafe-inline' is ignored once a nonce or hash is present
+	return !hasNonceOrHash && containsFold(sources, "'unsafe-inline'")
+}
+
+func eventHandlerAllowed(policy types.CSPPolicy) bool {
+	sources, ok := scriptSources(policy)
+	if !ok {
+		return true
+	}
+	for _, source := range sources {
+		if isNonceSource(source) || isHashSource(source) {
+			return false
+		}
+	}
+	return containsFold(sources, "'unsafe-inline'")
+}
+
+func hashMatches(source, content string) bool {
+	algorithm, digest, _ := strings.Cut(strings.Trim(source, "'"), "-")
+	var sum []byte
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		h := sha256.Sum256([]byte(content))
+		sum = h[:]
+	case "sha384":
+		h := sha512.Sum384([]byte(content))
+		sum = h[:]
+	case "sha512":
+		h := sha512.Sum512([]byte(content))
+		sum = h[:]
+	}
+	return base64.StdEncoding.EncodeToString(sum) == digest
+}
+
+func externalScriptAllowed(policy types.CSPPolicy, scriptURL, pageURL *url.URL, nonce string) bool {
+	sources, ok := scriptSources(policy)
+	if !ok {
+		return true
+	}
+	for _, source := range sources {
+		if nonceMatches(source, nonce) {
+			return true
+		}
+	}
+	// With 'strict-dynamic' host and scheme sources are ignored
+	if containsFold(sources, "'strict-dynamic'") {
+		return false
+	}
+	for _, source := range sources {
+		if matchesCSPSource(source, scriptURL, pageURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCSPSource implements the CSP source expression matching for a URL
+func matchesCSPSource(source string, target, pageURL *url.URL) bool {
+	lower := strings.ToLower(source)
+	switch {
+	case lower == "*":
+		return target.Scheme == "http" || target.Scheme == "https" || target.Scheme == "ws" || target.Scheme == "wss"
+	case lower == "'self'":
+		return sameOrigin(target, pageURL) || (pageURL.Scheme == "http" && target.Scheme == "https" && strings.EqualFold(target.Host, pageURL.Host))
+	case strings.HasPrefix(lower, "'"):
+		return false
+	case strings.HasSuffix(lower, ":") && !strings.Contains(lower, "/"):
+		scheme := strings.TrimSuffix(lower, ":")
+		return target.Scheme == scheme || (scheme == "http" && target.Scheme == "https")
+	}
+
+	scheme := ""
+	rest := lower
+	if idx := strings.Index(rest, "://"); idx >= 0 {
+		scheme, rest = rest[:idx], rest[idx+3:]
+	}
+	hostPort, path := rest, ""
+	if idx := strings.Index(rest, "/"); idx >= 0 {
+		hostPort, path = rest[:idx], rest[idx:]
+	}
+	host, port := hostPort, ""
+	if idx := strings.LastIndex(hostPort, ":"); idx >= 0 {
+		host, port = hostPort[:idx], hostPort[idx+1:]
+	}
+
+	if scheme == "" {
+		scheme = pageURL.Scheme
+	}
+	if target.Scheme != scheme && !(scheme == "http" && target.Scheme == "https") && !(scheme == "ws" && target.Scheme == "wss") {
+		return false
+	}
+
+	targetHost := strings.ToLower(target.Hostname())
+	if strings.HasPrefix(host, "*.") {
+		if !strings.HasSuffix(targetHost, host[1:]) {
+			return false
+		}
+	} else if host != targetHost {
+		return false
+	}
+
+	if port != "*" {
+		targetPort := target.Port()
+		if targetPort == "" {
+			targetPort = defaultPort(target.Scheme)
+		}
+		if port == "" {
+			port = defaultPort(scheme)
+			// An http source without a port also matches the upgraded https default port
+			if scheme == "http" && target.Scheme == "https" && targetPort == "443" {
+				port = "443"
+			}
+		}
+		if port != targetPort {
+			return false
+		}
+	}
+
+	if path != "" {
+		if strings.HasSuffix(path, "/") {
+			return strings.HasPrefix(target.Path, path)
+		}
+		return target.Path == path
+	}
+	return true
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	portA, portB := a.Port(), b.Port()
+	if portA == "" {
+		portA = defaultPort(a.Scheme)
+	}
+	if portB == "" {
+		portB = defaultPort(b.Scheme)
+	}
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Hostname(), b.Hostname()) && portA == portB
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	}
+	return ""
+}
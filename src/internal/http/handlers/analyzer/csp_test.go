@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_parseCSP(t *testing.T) {
+	got := parseCSP("default-src 'self'; script-src 'self' https://cdn.example.com; script-src *, img-src *", types.CSPSourceHeader)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, []string{"'self'", "https://cdn.example.com"}, got[0].Directives["script-src"])
+	assert.Equal(t, []string{"*"}, got[1].Directives["img-src"])
+	assert.False(t, got[0].ReportOnly)
+}
+
+func Test_checkPolicyWeaknesses(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		source   string
+		severity string
+		check    string
+	}{
+		{name: "Unsafe inline", policy: "script-src 'self' 'unsafe-inline'", severity: types.SeverityError, check: "csp-unsafe-inline"},
+		{name: "Unsafe eval", policy: "script-src 'self' 'unsafe-eval'", severity: types.SeverityWarning, check: "csp-unsafe-eval"},
+		{name: "Wildcard script source", policy: "script-src *", severity: types.SeverityError, check: "csp-wildcard"},
+		{name: "Scheme-only script source", policy: "script-src https:", severity: types.SeverityError, check: "csp-wildcard"},
+		{name: "Missing object-src", policy: "script-src 'self'", severity: types.SeverityWarning, check: "csp-object-src"},
+		{name: "Missing base-uri", policy: "default-src 'none'", severity: types.SeverityWarning, check: "csp-base-uri"},
+		{name: "HTTP source", policy: "default-src 'self'; img-src http://images.example.com", severity: types.SeverityWarning, check: "csp-http-source"},
+		{name: "Short nonce", policy: "script-src 'nonce-abc'", severity: types.SeverityWarning, check: "csp-nonce"},
+		{name: "Malformed hash", policy: "script-src 'sha256-abc'", severity: types.SeverityError, check: "csp-hash"},
+		{name: "Strict dynamic without nonce", policy: "script-src 'strict-dynamic'", severity: types.SeverityWarning, check: "csp-strict-dynamic"},
+		{name: "No script restriction", policy: "img-src 'self'", severity: types.SeverityError, check: "csp-script-src"},
+		{name: "Meta frame-ancestors", policy: "default-src 'self'; frame-ancestors 'none'", source: types.CSPSourceMeta, severity: types.SeverityWarning, check: "csp-meta"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source == "" {
+				source = types.CSPSourceHeader
+			}
+			var findings []types.Finding
+			checkPolicyWeaknesses(parseCSP(tt.policy, source)[0], &findings)
+			assert.True(t, hasFinding(findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, findings)
+		})
+	}
+}
+
+func Test_checkPolicyWeaknesses_Strict(t *testing.T) {
+	var findings []types.Finding
+	policy := "script-src 'nonce-r4nd0mN0nc3V4lu3' 'strict-dynamic' 'unsafe-inline' https:; object-src 'none'; base-uri 'none'"
+
+	checkPolicyWeaknesses(parseCSP(policy, types.CSPSourceHeader)[0], &findings)
+
+	// 'unsafe-inline' and https: are fallbacks ignored by browsers that support nonces
+	assert.False(t, hasFinding(findings, types.SeverityError, "csp-unsafe-inline"))
+	assert.False(t, hasFinding(findings, types.SeverityWarning, "csp-object-src"))
+}
+
+func Test_checkPolicyWeaknesses_Order(t *testing.T) {
+	policy := parseCSP("img-src *; style-src http://cdn.example.com; font-src *; connect-src http://api.example.com; media-src *", types.CSPSourceHeader)[0]
+	var first []types.Finding
+	checkPolicyWeaknesses(policy, &first)
+
+	for i := 0; i < 20; i++ {
+		var findings []types.Finding
+		checkPolicyWeaknesses(policy, &findings)
+		assert.Equal(t, first, findings)
+	}
+	var insecure []string
+	for _, finding := range first {
+		if finding.Check == "csp-http-source" {
+			insecure = append(insecure, finding.Message)
+		}
+	}
+	if assert.Len(t, insecure, 2) {
+		assert.Contains(t, insecure[0], "connect-src")
+		assert.Contains(t, insecure[1], "style-src")
+	}
+}
+
+func Test_evaluateCSP_PageScripts(t *testing.T) {
+	inline := "console.log('hashed')"
+	sum := sha256.Sum256([]byte(inline))
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+
+	html := `<html><head>
+		<meta http-equiv="Content-Security-Policy" content="img-src 'self'">
+		<script>alert('blocked')</script>
+		<script>` + inline + `</script>
+		<script nonce="r4nd0mN0nc3V4lu3">var allowed = true</script>
+		<script type="application/ld+json">{"@type": "Thing"}</script>
+		<script src="/app.js"></script>
+		<script src="https://cdn.example.net/lib.js"></script>
+		<script src="https://tracker.example.org/t.js"></script>
+	</head><body><button onclick="go()">Go</button></body></html>`
+	header := http.Header{
+		"Content-Security-Policy":             {"script-src 'self' https://cdn.example.net 'nonce-r4nd0mN0nc3V4lu3' 'sha256-" + hash + "'; object-src 'none'; base-uri 'self'"},
+		"Content-Security-Policy-Report-Only": {"script-src 'none'"},
+	}
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	pageURL, _ := url.Parse("https://example.com/")
+
+	got := evaluateCSP(doc, header, pageURL)
+
+	assert.Len(t, got.Policies, 3)
+	assert.Equal(t, 1, got.BlockedInlineScripts)
+	assert.Equal(t, 1, got.BlockedEventHandlers)
+	assert.Equal(t, []string{"https://tracker.example.org"}, got.BlockedScriptOrigins)
+}
+
+func Test_scriptAllowed_Nonce(t *testing.T) {
+	policy := parseCSP("script-src 'NONCE-r4nd0mN0nc3V4lu3' 'strict-dynamic'", types.CSPSourceHeader)[0]
+	pageURL, _ := url.Parse("https://example.com/")
+	scriptURL, _ := url.Parse("https://cdn.example.net/lib.js")
+
+	tests := []struct {
+		nonce string
+		want  bool
+	}{
+		{nonce: "r4nd0mN0nc3V4lu3", want: true},
+		// Nonces are base64 and compared exactly
+		{nonce: "R4ND0MN0NC3V4LU3", want: false},
+		{nonce: "r4nd0mn0nc3v4lu3", want: false},
+		{nonce: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nonce, func(t *testing.T) {
+			assert.Equal(t, tt.want, inlineScriptAllowed(policy, "var a = 1", tt.nonce))
+			assert.Equal(t, tt.want, externalScriptAllowed(policy, scriptURL, pageURL, tt.nonce))
+		})
+	}
+}
+
+func Test_matchesCSPSource(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/")
+	tests := []struct {
+		source string
+		target string
+		want   bool
+	}{
+		{source: "'self'", target: "https://example.com/a.js", want: true},
+		{source: "'self'", target: "https://cdn.example.com/a.js", want: false},
+		{source: "*.example.com", target: "https://cdn.example.com/a.js", want: true},
+		{source: "*.example.com", target: "https://example.com/a.js", want: false},
+		{source: "https:", target: "https://any.test/a.js", want: true},
+		{source: "http://cdn.test", target: "https://cdn.test/a.js", want: true},
+		{source: "cdn.test:8443", target: "https://cdn.test/a.js", want: false},
+		{source: "cdn.test:*", target: "https://cdn.test:8443/a.js", want: true},
+		{source: "cdn.test/js/", target: "https://cdn.test/js/a.js", want: true},
+		{source: "cdn.test/js/a.js", target: "https://cdn.test/js/b.js", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+" "+tt.target, func(t *testing.T) {
+			target, _ := url.Parse(tt.target)
+			assert.Equal(t, tt.want, matchesCSPSource(tt.source, target, pageURL))
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/html"
+)
+
+// doctypeVersions maps public identifier prefixes to a precise HTML version.
+// More specific prefixes must come before the ones they start with.
+var doctypeVersions = []struct {
+	prefix  string
+	version string
+}{
+	{"-//W3C//DTD XHTML 1.1//", "XHTML 1.1"},
+	{"-//W3C//DTD XHTML 1.0 Strict//", "XHTML 1.0 Strict"},
+	{"-//W3C//DTD XHTML 1.0 Transitional//", "XHTML 1.0 Transitional"},
+	{"-//W3C//DTD XHTML 1.0 Frameset//", "XHTML 1.0 Frameset"},
+	{"-//W3C//DTD XHTML Basic 1.", "XHTML Basic"},
+	{"-//W3C//DTD HTML 4.01 Transitional//", "HTML 4.01 Transitional"},
+	{"-//W3C//DTD HTML 4.01 Frameset//", "HTML 4.01 Frameset"},
+	{"-//W3C//DTD HTML 4.01//", "HTML 4.01 Strict"},
+	{"-//W3C//DTD HTML 4.0 Transitional//", "HTML 4.0 Transitional"},
+	{"-//W3C//DTD HTML 4.0 Frameset//", "HTML 4.0 Frameset"},
+	{"-//W3C//DTD HTML 4.0//", "HTML 4.0 Strict"},
+	{"-//W3C//DTD HTML 3.2", "HTML 3.2"},
+	{"-//IETF//DTD HTML 2.0", "HTML 2.0"},
+}
+
+// quirksPublicIDPrefixes trigger quirks mode as defined by the HTML parsing spec
+var quirksPublicIDPrefixes = []string{
+	"+//Silmaril//dtd html Pro v0r11 19970101//",
+	"-//AS//DTD HTML 3.0 asWedit + extensions//",
+	"-//AdvaSoft Ltd//DTD HTML 3.0 asWedit + extensions//",
+	"-//IETF//DTD HTML 2.0 Level 1//",
+	"-//IETF//DTD HTML 2.0 Level 2//",
+	"-//IETF//DTD HTML 2.0 Strict Level 1//",
+	"-//IETF//DTD HTML 2.0 Strict Level 2//",
+	"-//IETF//DTD HTML 2.0 Strict//",
+	"-//IETF//DTD HTML 2.0//",
+	"-//IETF//DTD HTML 2.1E//",
+	"-//IETF//DTD HTML 3.0//",
+	"-//IETF//DTD HTML 3.2 Final//",
+	"-//IETF//DTD HTML 3.2//",
+	"-//IETF//DTD HTML 3//",
+	"-//IETF//DTD HTML Level 0//",
+	"-//IETF//DTD HTML Level 1//",
+	"-//IETF//DTD HTML Level 2//",
+	"-//IETF//DTD HTML Level 3//",
+	"-//IETF//DTD HTML Strict Level 0//",
+	"-//IETF//DTD HTML Strict Level 1//",
+	"-//IETF//DTD HTML Strict Level 2//",
+	"-//IETF//DTD HTML Strict Level 3//",
+	"-//IETF//DTD HTML Strict//",
+	"-//IETF//DTD HTML//",
+	"-//Metrius//DTD Metrius Presentational//",
+	"-//Microsoft//DTD Internet Explorer 2.0 HTML Strict//",
+	"-//Microsoft//DTD Internet Explorer 2.0 HTML//",
+	"-//Microsoft//DTD Internet Explorer 2.0 Tables//",
+	"-//Microsoft//DTD Internet Explorer 3.0 HTML Strict//",
+	"-//Microsoft//DTD Internet Explorer 3.0 HTML//",
+	"-//Microsoft//DTD Internet Explorer 3.0 Tables//",
+	"-//Netscape Comm. Corp.//DTD HTML//",
+	"-//Netscape Comm. Corp.//DTD Strict HTML//",
+	"-//O'Reilly and Associates//DTD HTML 2.0//",
+	"-//O'Reilly and Associates//DTD HTML Extended 1.0//",
+	"-//O'Reilly and Associates//DTD HTML Extended Relaxed 1.0//",
+	"-//SQ//DTD HTML 2.0 HoTMetaL + extensions//",
+	"-//SoftQuad Software//DTD HoTMetaL PRO 6.0::19990601::extensions to HTML 4.0//",
+	"-//SoftQuad//DTD HoTMetaL PRO 4.0::19971010::extensions to HTML 4.0//",
+	"-//Spyglass//DTD HTML 2.0 Extended//",
+	"-//Sun Microsystems Corp.//DTD HotJava HTML//",
+	"-//Sun Microsystems Corp.//DTD HotJava Strict HTML//",
+	"-//W3C//DTD HTML 3 1995-03-24//",
+	"-//W3C//DTD HTML 3.2 Draft//",
+	"-//W3C//DTD HTML 3.2 Final//",
+	"-//W3C//DTD HTML 3.2//",
+	"-//W3C//DTD HTML 3.2S Draft//",
+	"-//W3C//DTD HTML 4.0 Frameset//",
+	"-//W3C//DTD HTML 4.0 Transitional//",
+	"-//W3C//DTD HTML Experimental 19960712//",
+	"-//W3C//DTD HTML Experimental 970421//",
+	"-//W3C//DTD W3 HTML//",
+	"-//W3O//DTD W3 HTML 3.0//",
+	"-//WebTechs//DTD Mozilla HTML 2.0//",
+	"-//WebTechs//DTD Mozilla HTML//",
+}
+
+var quirksPublicIDs = []string{"-//W3O//DTD W3 HTML Strict 3.0//EN//", "-/W3C/DTD HTML 4.0 Transitional/EN", "HTML"}
+
+const quirksSystemID = "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd"
+
+// Public identifiers whose rendering mode depends on whether a system identifier is present
+var (
+	html401LoosePrefixes = []string{"-//W3C//DTD HTML 4.01 Frameset//", "-//W3C//DTD HTML 4.01 Transitional//"}
+	xhtmlLoosePrefixes   = []string{"-//W3C//DTD XHTML 1.0 Frameset//", "-//W3C//DTD XHTML 1.0 Transitional//"}
+)
+
+// parseDoctype reads the DocumentType node of the parsed document and works out
+// the HTML version and the mode a browser would render it in
+func parseDoctype(doc *goquery.Document) *types.Doctype {
+	logrus.Debug("Parsing doctype")
+	doctype := &types.Doctype{Version: "Unknown", Mode: types.RenderingModeQuirks}
+
+	node := findDoctypeNode(doc)
+	if node == nil {
+		doctype.Version = "No doctype"
+		return doctype
+	}
+
+	doctype.Present = true
+	doctype.Name = strings.ToLower(node.Data)
+	doctype.PublicID, _ = doctypeAttr(node, "public")
+	systemID, hasSystemID := doctypeAttr(node, "system")
+	doctype.SystemID = systemID
+
+	doctype.Version = doctypeVersion(doctype)
+	doctype.Mode = renderingMode(doctype, hasSystemID)
+	logrus.Debug("Doctype parsed: ", doctype.Version, " in ", doctype.Mode, " mode")
+	return doctype
+}
+
+func findDoctypeNode(doc *goquery.Document) *html.Node {
+	for _, root := range doc.Nodes {
+		for node := root.FirstChild; node != nil; node = node.NextSibling {
+			if node.Type == html.DoctypeNode {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// doctypeAttr returns a public or system identifier and whether it was declared at all
+func doctypeAttr(node *html.Node, key string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func doctypeVersion(doctype *types.Doctype) string {
+	if doctype.Name != "html" {
+		return "Unknown"
+	}
+	if doctype.PublicID == "" {
+		if doctype.SystemID == "" || strings.EqualFold(doctype.SystemID, "about:legacy-compat") {
+			return "HTML5"
+		}
+		return "Unknown"
+	}
+	for _, v := range doctypeVersions {
+		if hasPrefixFold(doctype.PublicID, v.prefix) {
+			return v.version
+		}
+	}
+	return "Unknown"
+}
+
+func renderingMode(doctype *types.Doctype, hasSystemID bool) string {
+	publicID := doctype.PublicID
+	switch {
+	case doctype.Name != "html",
+		containsFold(quirksPublicIDs, publicID),
+		strings.EqualFold(doctype.SystemID, quirksSystemID),
+		anyPrefixFold(publicID, quirksPublicIDPrefixes),
+		!hasSystemID && anyPrefixFold(publicID, html401LoosePrefixes):
+		return types.RenderingModeQuirks
+	case anyPrefixFold(publicID, xhtmlLoosePrefixes),
+		hasSystemID && anyPrefixFold(publicID, html401LoosePrefixes):
+		return types.RenderingModeAlmostStandards
+	}
+	return types.RenderingModeStandards
+}
+
+// htmlVersionLabel maps a parsed doctype onto the coarse version names reported
+// in the htmlVersion field
+func htmlVersionLabel(doctype *types.Doctype) string {
+	switch {
+	case doctype.Version == "HTML5":
+		return "HTML5"
+	case strings.HasPrefix(doctype.Version, "HTML 4"):
+		return "HTML 4"
+	case strings.HasPrefix(doctype.Version, "XHTML"):
+		return "XHTML"
+	}
+	return "Unknown HTML version"
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func anyPrefixFold(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPrefixFold(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_parseDoctype(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		present bool
+		version string
+		mode    string
+	}{
+		{name: "HTML5", html: "<!DOCTYPE html>", present: true, version: "HTML5", mode: types.RenderingModeStandards},
+		{name: "HTML5 lowercase with whitespace", html: "<!doctype   html  >", present: true, version: "HTML5", mode: types.RenderingModeStandards},
+		{name: "Legacy compat", html: `<!DOCTYPE html SYSTEM "about:legacy-compat">`, present: true, version: "HTML5", mode: types.RenderingModeStandards},
+		{name: "HTML 4.01 Strict", html: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`, present: true, version: "HTML 4.01 Strict", mode: types.RenderingModeStandards},
+		{name: "HTML 4.01 Transitional with system id", html: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`, present: true, version: "HTML 4.01 Transitional", mode: types.RenderingModeAlmostStandards},
+		{name: "HTML 4.01 Transitional without system id", html: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN">`, present: true, version: "HTML 4.01 Transitional", mode: types.RenderingModeQuirks},
+		{name: "HTML 4.01 Frameset", html: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Frameset//EN" "http://www.w3.org/TR/html4/frameset.dtd">`, present: true, version: "HTML 4.01 Frameset", mode: types.RenderingModeAlmostStandards},
+		{name: "XHTML 1.0 Strict", html: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`, present: true, version: "XHTML 1.0 Strict", mode: types.RenderingModeStandards},
+		{name: "XHTML 1.0 Transitional", html: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`, present: true, version: "XHTML 1.0 Transitional", mode: types.RenderingModeAlmostStandards},
+		{name: "XHTML 1.1", html: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`, present: true, version: "XHTML 1.1", mode: types.RenderingModeStandards},
+		{name: "HTML 3.2", html: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`, present: true, version: "HTML 3.2", mode: types.RenderingModeQuirks},
+		{name: "Missing doctype", html: "", present: false, version: "No doctype", mode: types.RenderingModeQuirks},
+		{name: "Doctype text in body", html: "<p>&lt;!DOCTYPE html&gt;</p>", present: false, version: "No doctype", mode: types.RenderingModeQuirks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html + "<html><head></head><body></body></html>"))
+			got := parseDoctype(doc)
+			assert.Equal(t, tt.present, got.Present)
+			assert.Equal(t, tt.version, got.Version)
+			assert.Equal(t, tt.mode, got.Mode)
+		})
+	}
+}
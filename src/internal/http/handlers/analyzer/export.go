@@ -0,0 +1,162 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// csvFlushEvery is the number of rows written before the CSV buffer is flushed
+// to the client, so large exports are streamed instead of held in memory
+const csvFlushEvery = 500
+
+var metricsCSVHeader = []string{
+	"url", "html_version", "title",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+	"internal_links", "external_links", "accessible_external_links", "broken_external_links",
+	"has_login_form",
+}
+
+var linksCSVHeader = []string{"page_url", "href", "type", "status", "anchor_text"}
+
+// ExportMetricsCSV analyzes a URL and returns the page-level metrics as CSV
+func ExportMetricsCSV(w http.ResponseWriter, r *http.Request) {
+	pageURL, result, ok := handleAnalysisRequest(w, r)
+	if !ok {
+		return
+	}
+
+	setAttachmentHeaders(w, "text/csv", "metrics.csv")
+	if err := writeMetricsCSV(w, pageURL, result); err != nil {
+		logrus.Error("Failed to write metrics CSV: ", err)
+	}
+}
+
+// ExportLinksCSV analyzes a URL and returns one CSV row per link found on the page
+func ExportLinksCSV(w http.ResponseWriter, r *http.Request) {
+	pageURL, result, ok := handleAnalysisRequest(w, r)
+	if !ok {
+		return
+	}
+
+	setAttachmentHeaders(w, "text/csv", "links.csv")
+	if err := writeLinksCSV(w, pageURL, result.Links); err != nil {
+		logrus.Error("Failed to write links CSV: ", err)
+	}
+}
+
+// ExportBundle analyzes a URL and returns a zip archive containing both CSV files
+func ExportBundle(w http.ResponseWriter, r *http.Request) {
+	pageURL, result, ok := handleAnalysisRequest(w, r)
+	if !ok {
+		return
+	}
+
+	setAttachmentHeaders(w, "application/zip", "analysis.zip")
+	if err := writeBundle(w, pageURL, result); err != nil {
+		logrus.Error("Failed to write export bundle: ", err)
+	}
+}
+
+// setAttachmentHeaders marks the response as a file download
+func setAttachmentHeaders(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+}
+
+// writeMetricsCSV writes the header and a single metrics row for the analyzed page
+func writeMetricsCSV(w io.Writer, pageURL string, result *types.AnalyzeResultes) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(metricsCSVHeader); err != nil {
+		return err
+	}
+
+	row := []string{escapeCSVCell(pageURL), escapeCSVCell(result.HTMLVersion), escapeCSVCell(result.Title)}
+	for i := 1; i <= 6; i++ {
+		row = append(row, strconv.Itoa(result.Headings["h"+strconv.Itoa(i)]))
+	}
+	row = append(row,
+		strconv.Itoa(result.InternalLinks),
+		strconv.Itoa(result.ExternalLinks),
+		strconv.Itoa(result.AccessibleExternalLinks),
+		strconv.Itoa(result.BrokenExternalLinks),
+		strconv.FormatBool(result.HasLoginForm),
+	)
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeLinksCSV writes one row per link, flushing periodically
+func writeLinksCSV(w io.Writer, pageURL string, links []types.LinkResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(linksCSVHeader); err != nil {
+		return err
+	}
+
+	for i, link := range links {
+		if err := writer.Write([]string{
+			escapeCSVCell(pageURL), escapeCSVCell(link.Href), link.Type, link.Status, escapeCSVCell(link.AnchorText),
+		}); err != nil {
+			return err
+		}
+		if (i+1)%csvFlushEvery == 0 {
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return err
+			}
+			flushResponse(w)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// escapeCSVCell prefixes values taken from the analyzed page with a quote when
+// they start like a formula, so spreadsheets show them as text instead of running them
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// writeBundle streams a zip archive with metrics.csv and links.csv
+func writeBundle(w io.Writer, pageURL string, result *types.AnalyzeResultes) error {
+	archive := zip.NewWriter(w)
+
+	metricsFile, err := archive.Create("metrics.csv")
+	if err != nil {
+		return err
+	}
+	if err := writeMetricsCSV(metricsFile, pageURL, result); err != nil {
+		return err
+	}
+
+	linksFile, err := archive.Create("links.csv")
+	if err != nil {
+		return err
+	}
+	if err := writeLinksCSV(linksFile, pageURL, result.Links); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// flushResponse pushes buffered data to the client when the writer supports it
+func flushResponse(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
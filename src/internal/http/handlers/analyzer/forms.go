@@ -0,0 +1,367 @@
+package analyzer
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/html"
+)
+
+// minFormConfidence is the score below which a form is left unclassified
+const minFormConfidence = 0.3
+
+var (
+	csrfFieldRegex     = regexp.MustCompile(`(?i)(csrf|xsrf|authenticity_token|requestverificationtoken|^_?token$|nonce)`)
+	usernameFieldRegex = regexp.MustCompile(`(?i)(user|login|email|account|identifier)`)
+	confirmFieldRegex  = regexp.MustCompile(`(?i)(confirm|repeat|again|password2|passwd2|retype)`)
+	searchFieldRegex   = regexp.MustCompile(`(?i)^(q|query|s|search|keywords?|term)$`)
+	paymentFieldRegex  = regexp.MustCompile(`(?i)(card.?number|cc.?num|cvv|cvc|expir)`)
+	oauthTextRegex     = regexp.MustCompile(`(?i)\b(log ?in|sign ?in|sign ?up|continue|connect) with\s+(\w+)\b`)
+	nextStepRegex      = regexp.MustCompile(`(?i)\b(next|continue)\b`)
+)
+
+// formKeywords are matched against submit button text and the form action
+var formKeywords = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{types.FormLogin, regexp.MustCompile(`(?i)\b(log ?in|sign ?in|signin|login|session|auth)\b`)},
+	{types.FormSignup, regexp.MustCompile(`(?i)\b(sign ?up|signup|register|registration|create (an )?account|join)\b`)},
+	{types.FormPasswordReset, regexp.MustCompile(`(?i)\b(reset|forgot|recover)\b`)},
+	{types.FormSearch, regexp.MustCompile(`(?i)\bsearch\b`)},
+	{types.FormNewsletter, regexp.MustCompile(`(?i)\b(subscribe|newsletter)\b`)},
+	{types.FormPayment, regexp.MustCompile(`(?i)\b(pay|payment|checkout|place order|purchase)\b`)},
+	{types.FormContact, regexp.MustCompile(`(?i)\b(contact|send message|enquiry|inquiry)\b`)},
+}
+
+// oauthProviders maps provider names to their authorization endpoints, matched
+// against the host and path of a link
+var oauthProviders = []struct {
+	name     string
+	endpoint *regexp.Regexp
+}{
+	{"Google", regexp.MustCompile(`^accounts\.google\.com/`)},
+	{"Facebook", regexp.MustCompile(`^([a-z0-9-]+\.)*facebook\.com/(v\d+\.\d+/)?dialog/oauth\b`)},
+	{"GitHub", regexp.MustCompile(`^github\.com/login/oauth\b`)},
+	{"Apple", regexp.MustCompile(`^appleid\.apple\.com/`)},
+	{"Microsoft", regexp.MustCompile(`^login\.(microsoftonline\.com|live\.com)/`)},
+	{"Twitter", regexp.MustCompile(`^(api\.twitter\.com/oauth|([a-z0-9-]+\.)*twitter\.com/i/oauth2)\b`)},
+	{"LinkedIn", regexp.MustCompile(`^([a-z0-9-]+\.)*linkedin\.com/oauth\b`)},
+}
+
+// formScore accumulates classification evidence for one form
+type formScore struct {
+	scores   map[string]float64
+	evidence map[string][]string
+}
+
+func (f *formScore) add(class string, weight float64, evidence string) {
+	f.scores[class] += weight
+	f.evidence[class] = append(f.evidence[class], evidence)
+}
+
+// inventoryForms lists every form on the page and classifies it. Fields that
+// belong to no form are grouped into a single implicit form.
+func inventoryForms(doc *goquery.Document, pageURL *url.URL) *types.FormsReport {
+	logrus.Debug("Inventorying forms")
+	report := &types.FormsReport{Forms: []types.FormInfo{}, OAuthProviders: findOAuthProviders(doc.Selection)}
+
+	owners := fieldsByForm(doc.Selection)
+	doc.Find("form").Each(func(i int, s *goquery.Selection) {
+		fields := doc.FindNodes(owners[s.Nodes[0]]...)
+		report.Forms = append(report.Forms, describeForm(s, fields, pageURL, false))
+	})
+
+	orphans := doc.FindNodes(owners[nil]...)
+	if orphans.FilterFunction(isUserField).Length() > 0 {
+		report.Forms = append(report.Forms, describeForm(doc.Find("body").First(), orphans, pageURL, true))
+	}
+
+	logrus.Debug("Found ", len(report.Forms), " forms")
+	return report
+}
+
+// fieldsByForm groups the fields under root by the form they belong to, in
+// document order. A field belongs to the form its form attribute names, or to
+// its closest form ancestor without one; fields of no form are under nil,
+// including those whose form attribute names no form.
+func fieldsByForm(root *goquery.Selection) map[*html.Node][]*html.Node {
+	ids := make(map[string]*html.Node)
+	root.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		// The first element with an id wins, as with getElementById
+		if id := s.AttrOr("id", ""); ids[id] == nil {
+			ids[id] = s.Nodes[0]
+		}
+	})
+
+	owners := make(map[*html.Node][]*html.Node)
+	root.Find("input, select, textarea").Each(func(i int, field *goquery.Selection) {
+		var owner *html.Node
+		if id, ok := field.Attr("form"); ok {
+			if node := ids[id]; node != nil && node.Type == html.ElementNode && node.Data == "form" {
+				owner = node
+			}
+		} else if form := field.Closest("form"); form.Length() > 0 {
+			owner = form.Nodes[0]
+		}
+		owners[owner] = append(owners[owner], field.Nodes[0])
+	})
+	return owners
+}
+
+// formFields returns the fields that belong to form, wherever they are in the document
+func formFields(form *goquery.Selection) *goquery.Selection {
+	root := form.Nodes[0]
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	doc := goquery.NewDocumentFromNode(root)
+	return doc.FindNodes(fieldsByForm(doc.Selection)[form.Nodes[0]]...)
+}
+
+// isLoginPage reports whether the inventory contains a login form or OAuth sign-in buttons
+func isLoginPage(report *types.FormsReport) bool {
+	if len(report.OAuthProviders) > 0 {
+		return true
+	}
+	for _, form := range report.Forms {
+		if form.Classification == types.FormLogin {
+			return true
+		}
+	}
+	return false
+}
+
+func describeForm(s *goquery.Selection, fields *goquery.Selection, pageURL *url.URL, implicit bool) types.FormInfo {
+	form := types.FormInfo{
+		Selector: cssPath(s),
+		Implicit: implicit,
+		Method:   strings.ToUpper(strings.TrimSpace(s.AttrOr("method", "GET"))),
+		Action:   resolveFormAction(s.AttrOr("action", ""), pageURL),
+		Fields:   []types.FormField{},
+	}
+	if implicit {
+		form.Method = ""
+	}
+
+	fields.Each(func(i int, field *goquery.Selection) {
+		f := types.FormField{
+			Name:         field.AttrOr("name", field.AttrOr("id", "")),
+			Type:         fieldType(field),
+			Autocomplete: strings.ToLower(strings.TrimSpace(field.AttrOr("autocomplete", ""))),
+		}
+		_, f.Required = field.Attr("required")
+		form.Fields = append(form.Fields, f)
+		if f.Type == "hidden" && csrfFieldRegex.MatchString(f.Name) {
+			form.CSRFTokens = append(form.CSRFTokens, f.Name)
+		}
+	})
+
+	classifyForm(&form, s)
+	return form
+}
+
+// resolveFormAction returns the absolute URL the form submits to; an empty action
+// submits to the page itself
+func resolveFormAction(action string, pageURL *url.URL) string {
+	action = strings.TrimSpace(action)
+	if pageURL == nil {
+		return action
+	}
+	ref, err := url.Parse(action)
+	if err != nil {
+		return action
+	}
+	return pageURL.ResolveReference(ref).String()
+}
+
+func fieldType(field *goquery.Selection) string {
+	if name := goquery.NodeName(field); name != "input" {
+		return name
+	}
+	return strings.ToLower(strings.TrimSpace(field.AttrOr("type", "text")))
+}
+
+// isUserField reports whether the user can type into the field
+func isUserField(i int, s *goquery.Selection) bool {
+	return isUserFieldType(fieldType(s))
+}
+
+func isUserFieldType(fieldType string) bool {
+	switch fieldType {
+	case "hidden", "submit", "button", "reset", "image":
+		return false
+	}
+	return true
+}
+
+// classifyForm scores the form against every known purpose and keeps the best match
+func classifyForm(form *types.FormInfo, s *goquery.Selection) {
+	score := &formScore{scores: make(map[string]float64), evidence: make(map[string][]string)}
+
+	passwords, confirms, visible, emails, textareas := 0, 0, 0, 0, 0
+	var usernameField string
+	for _, field := range form.Fields {
+		if !isUserFieldType(field.Type) {
+			continue
+		}
+		visible++
+		switch {
+		case field.Type == "password":
+			passwords++
+			if confirmFieldRegex.MatchString(field.Name) {
+				confirms++
+			}
+		case field.Type == "email":
+			emails++
+		case field.Type == "textarea":
+			textareas++
+		}
+		if field.Type != "password" && usernameField == "" &&
+			(usernameFieldRegex.MatchString(field.Name) || field.Autocomplete == "username") {
+			usernameField = field.Name
+		}
+
+		switch field.Autocomplete {
+		case "current-password":
+			score.add(types.FormLogin, 0.4, "autocomplete=current-password")
+		case "new-password":
+			score.add(types.FormSignup, 0.2, "autocomplete=new-password")
+			score.add(types.FormPasswordReset, 0.2, "autocomplete=new-password")
+		case "cc-number", "cc-exp", "cc-csc", "cc-name":
+			score.add(types.FormPayment, 0.5, "autocomplete="+field.Autocomplete)
+		}
+		if field.Type == "search" || searchFieldRegex.MatchString(field.Name) {
+			score.add(types.FormSearch, 0.5, "search field "+field.Name)
+		}
+		if paymentFieldRegex.MatchString(field.Name) {
+			score.add(types.FormPayment, 0.5, "payment field "+field.Name)
+		}
+	}
+
+	switch {
+	case passwords == 1:
+		score.add(types.FormLogin, 0.5, "single password field")
+	case passwords >= 2:
+		score.add(types.FormSignup, 0.4, "multiple password fields")
+		score.add(types.FormPasswordReset, 0.2, "multiple password fields")
+	}
+	if confirms > 0 {
+		score.add(types.FormSignup, 0.2, "password confirmation field")
+	}
+	if passwords > 0 && visible > 4 {
+		score.add(types.FormSignup, 0.3, "password with many other fields")
+	}
+	if textareas > 0 && (emails > 0 || usernameField != "") {
+		score.add(types.FormContact, 0.5, "message field with contact details")
+	}
+	if emails == 1 && visible == 1 && passwords == 0 {
+		score.add(types.FormNewsletter, 0.3, "single email field")
+		score.add(types.FormPasswordReset, 0.1, "single email field")
+	}
+
+	buttonText := submitText(s)
+	for _, kw := range formKeywords {
+		if buttonText != "" && kw.pattern.MatchString(buttonText) {
+			score.add(kw.class, 0.3, "submit text \""+buttonText+"\"")
+		}
+		if form.Action != "" && kw.pattern.MatchString(actionPath(form.Action)) {
+			score.add(kw.class, 0.3, "action "+actionPath(form.Action))
+		}
+	}
+
+	// Multi-step logins ask for the username first and the password on the next page
+	if passwords == 0 && usernameField != "" && visible <= 2 && textareas == 0 &&
+		(score.scores[types.FormLogin] > 0 || nextStepRegex.MatchString(buttonText)) {
+		score.add(types.FormLogin, 0.4, "username-only first step ("+usernameField+")")
+	}
+
+	if providers := findOAuthProviders(s); len(providers) > 0 && !form.Implicit {
+		score.add(types.FormLogin, 0.3, "OAuth buttons for "+strings.Join(providers, ", "))
+	}
+
+	form.Classification = types.FormUnknown
+	form.Evidence = []string{}
+	best := 0.0
+	for _, kw := range formKeywords {
+		if value := score.scores[kw.class]; value > best {
+			best = value
+			form.Classification = kw.class
+		}
+	}
+	if best < minFormConfidence {
+		form.Classification = types.FormUnknown
+		form.Confidence = 0
+		return
+	}
+	form.Confidence = math.Min(1, math.Round(best*100)/100)
+	form.Evidence = score.evidence[form.Classification]
+}
+
+// submitText returns the text of the form's submit controls
+func submitText(s *goquery.Selection) string {
+	var texts []string
+	s.Find("button, input[type='submit'], input[type='image']").Each(func(i int, b *goquery.Selection) {
+		if goquery.NodeName(b) == "button" {
+			if t := strings.ToLower(b.AttrOr("type", "submit")); t != "submit" {
+				return
+			}
+		}
+		text := strings.TrimSpace(b.Text())
+		if text == "" {
+			text = strings.TrimSpace(b.AttrOr("value", b.AttrOr("alt", b.AttrOr("aria-label", ""))))
+		}
+		if text != "" {
+			texts = append(texts, strings.Join(strings.Fields(text), " "))
+		}
+	})
+	return strings.Join(texts, " ")
+}
+
+func actionPath(action string) string {
+	parsed, err := url.Parse(action)
+	if err != nil {
+		return action
+	}
+	return parsed.Path
+}
+
+// findOAuthProviders returns the identity providers offered through "sign in
+// with" buttons or links to known authorization endpoints
+func findOAuthProviders(s *goquery.Selection) []string {
+	found := make(map[string]bool)
+	s.Find("button, a").Each(func(i int, el *goquery.Selection) {
+		if link, err := url.Parse(strings.TrimSpace(el.AttrOr("href", ""))); err == nil && link.Host != "" {
+			endpoint := strings.ToLower(link.Hostname() + link.EscapedPath())
+			for _, provider := range oauthProviders {
+				if provider.endpoint.MatchString(endpoint) {
+					found[provider.name] = true
+				}
+			}
+		}
+
+		// Only known providers count, "continue with checkout" is not a sign-in
+		match := oauthTextRegex.FindStringSubmatch(strings.Join(strings.Fields(el.Text()), " "))
+		if match == nil {
+			return
+		}
+		for _, provider := range oauthProviders {
+			if strings.EqualFold(provider.name, match[2]) || (provider.name == "Twitter" && strings.EqualFold(match[2], "x")) {
+				found[provider.name] = true
+			}
+		}
+	})
+
+	providers := make([]string, 0, len(found))
+	for name := range found {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_inventoryForms_Classification(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		class string
+	}{
+		{
+			name:  "Login",
+			html:  `<form method="post" action="/session"><input name="username"><input type="password" name="password" autocomplete="current-password"><button>Sign in</button></form>`,
+			class: types.FormLogin,
+		},
+		{
+			name:  "Multi-step login",
+			html:  `<form action="/lookup"><input type="email" name="identifier" autocomplete="username"><button>Next</button></form>`,
+			class: types.FormLogin,
+		},
+		{
+			name:  "Signup",
+			html:  `<form action="/register"><input name="email" type="email"><input type="password" name="password"><input type="password" name="password_confirm"><button>Create account</button></form>`,
+			class: types.FormSignup,
+		},
+		{
+			name:  "Password reset",
+			html:  `<form action="/password/forgot"><input type="email" name="email"><button>Reset password</button></form>`,
+			class: types.FormPasswordReset,
+		},
+		{
+			name:  "Search",
+			html:  `<form action="/find"><input type="search" name="q"></form>`,
+			class: types.FormSearch,
+		},
+		{
+			name:  "Newsletter",
+			html:  `<form action="/list"><input type="email" name="address"><button>Subscribe</button></form>`,
+			class: types.FormNewsletter,
+		},
+		{
+			name:  "Payment",
+			html:  `<form action="/orders"><input name="card_number" autocomplete="cc-number"><input name="cvc" autocomplete="cc-csc"><button>Pay now</button></form>`,
+			class: types.FormPayment,
+		},
+		{
+			name:  "Contact",
+			html:  `<form action="/contact"><input type="email" name="email"><textarea name="message"></textarea><button>Send</button></form>`,
+			class: types.FormContact,
+		},
+		{
+			name:  "Unknown",
+			html:  `<form><input name="colour"></form>`,
+			class: types.FormUnknown,
+		},
+	}
+
+	pageURL, _ := url.Parse("https://example.com/page")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			got := inventoryForms(doc, pageURL)
+			assert.Len(t, got.Forms, 1)
+			assert.Equal(t, tt.class, got.Forms[0].Classification, "evidence: %v", got.Forms[0].Evidence)
+			if tt.class != types.FormUnknown {
+				assert.GreaterOrEqual(t, got.Forms[0].Confidence, minFormConfidence)
+				assert.NotEmpty(t, got.Forms[0].Evidence)
+			}
+		})
+	}
+}
+
+func Test_inventoryForms_Details(t *testing.T) {
+	html := `<html><body><form method="post" action="login">
+		<input type="hidden" name="csrf_token" value="abc">
+		<input name="user" autocomplete="username" required>
+		<input type="password" name="pass">
+	</form></body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	pageURL, _ := url.Parse("https://example.com/account/")
+
+	got := inventoryForms(doc, pageURL).Forms[0]
+
+	assert.Equal(t, "POST", got.Method)
+	assert.Equal(t, "https://example.com/account/login", got.Action)
+	assert.Equal(t, "html > body > form", got.Selector)
+	assert.Equal(t, []string{"csrf_token"}, got.CSRFTokens)
+	assert.Equal(t, types.FormField{Name: "user", Type: "text", Autocomplete: "username", Required: true}, got.Fields[1])
+}
+
+func Test_inventoryForms_FormAttribute(t *testing.T) {
+	html := `<html><body>
+		<input type="email" name="email" form="signin">
+		<form id="search"><input name="q"><input type="password" name="password" form="signin"></form>
+		<form id="signin" method="post"><button type="submit">Sign in</button></form>
+		<input name="note" form="missing">
+	</body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	got := inventoryForms(doc, nil)
+
+	if assert.Len(t, got.Forms, 3) {
+		assert.Equal(t, []types.FormField{{Name: "q", Type: "text"}}, got.Forms[0].Fields)
+		assert.Equal(t, []types.FormField{{Name: "email", Type: "email"}, {Name: "password", Type: "password"}}, got.Forms[1].Fields)
+		assert.Equal(t, types.FormLogin, got.Forms[1].Classification)
+		// A form attribute naming no form leaves the field without one
+		assert.True(t, got.Forms[2].Implicit)
+		assert.Equal(t, []types.FormField{{Name: "note", Type: "text"}}, got.Forms[2].Fields)
+	}
+	assert.True(t, isLoginPage(got))
+}
+
+func Test_inventoryForms_OAuthProviders(t *testing.T) {
+	html := `<html><body>
+		<a href="https://accounts.google.com/o/oauth2/auth?client_id=x">Google</a>
+		<button>Continue with GitHub</button>
+	</body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	got := inventoryForms(doc, nil)
+
+	assert.Equal(t, []string{"GitHub", "Google"}, got.OAuthProviders)
+	assert.True(t, isLoginPage(got))
+}
+
+func Test_findOAuthProviders(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{name: "Versioned Facebook dialog", html: `<a href="https://www.facebook.com/v18.0/dialog/oauth?client_id=x">Facebook</a>`, want: []string{"Facebook"}},
+		{name: "Facebook dialog", html: `<a href="https://m.facebook.com/dialog/oauth">Facebook</a>`, want: []string{"Facebook"}},
+		{name: "Facebook page", html: `<a href="https://www.facebook.com/vogue">Vogue</a>`, want: []string{}},
+		{name: "Facebook videos", html: `<a href="https://www.facebook.com/videos/123">Watch</a>`, want: []string{}},
+		{name: "Provider in the query only", html: `<a href="https://example.com/share?u=accounts.google.com/">Share</a>`, want: []string{}},
+		{name: "Sign in with X", html: `<button>Sign in with X</button>`, want: []string{"Twitter"}},
+		{name: "Log in with Apple", html: `<a href="/auth/apple">Log in with Apple</a>`, want: []string{"Apple"}},
+		{name: "Connect with us", html: `<a href="/contact">Connect with us</a>`, want: []string{}},
+		{name: "Continue with checkout", html: `<button>Continue with checkout</button>`, want: []string{}},
+		{name: "Disconnect with", html: `<button>Disconnect with Google</button>`, want: []string{}},
+		{name: "Provider prefix", html: `<button>Sign in with Googlebot</button>`, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			assert.Equal(t, tt.want, findOAuthProviders(doc.Selection))
+			assert.Equal(t, len(tt.want) > 0, isLoginPage(inventoryForms(doc, nil)))
+		})
+	}
+}
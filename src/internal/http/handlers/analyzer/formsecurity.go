@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/publicsuffix"
+)
+
+// validPasswordAutocomplete lists the autocomplete tokens that make sense on a password field
+var validPasswordAutocomplete = toSet("", "off", "current-password", "new-password", "one-time-code")
+
+// auditFormSecurity reports forms that leak credentials or are open to CSRF
+func auditFormSecurity(report *types.FormsReport, pageURL *url.URL) {
+	logrus.Debug("Auditing form security")
+	report.Findings = []types.Finding{}
+	pageHTTPS := pageURL.Scheme == "https"
+
+	for _, form := range report.Forms {
+		passwords := formFieldsOfType(form, "password")
+		add := func(severity, check, format string, args ...interface{}) {
+			addFinding(&report.Findings, severity, check, format, args...)
+			report.Findings[len(report.Findings)-1].Selector = form.Selector
+		}
+
+		action, err := url.Parse(form.Action)
+		if err == nil && !form.Implicit {
+			if pageHTTPS && action.Scheme == "http" {
+				add(types.SeverityError, "insecure-action", "Form on an HTTPS page submits over plain HTTP to %s", form.Action)
+			}
+			if action.Host != "" && registrableDomain(action.Hostname()) != registrableDomain(pageURL.Hostname()) {
+				add(types.SeverityWarning, "cross-domain-action", "Form submits to another domain: %s", action.Hostname())
+			}
+		}
+
+		if len(passwords) > 0 {
+			if !pageHTTPS {
+				add(types.SeverityError, "password-over-http", "Password field on a page served over plain HTTP")
+			}
+			if form.Method == "GET" {
+				add(types.SeverityError, "password-in-get", "Form with a password field uses GET, so the password ends up in the URL")
+			}
+		}
+
+		if form.Classification == types.FormLogin && !form.Implicit && len(form.CSRFTokens) == 0 {
+			add(types.SeverityWarning, "missing-csrf", "Login form has no anti-CSRF token")
+		}
+
+		for _, field := range passwords {
+			switch {
+			case !validPasswordAutocomplete[field.Autocomplete]:
+				add(types.SeverityWarning, "autocomplete-misuse", "Password field %q has autocomplete=%q", field.Name, field.Autocomplete)
+			case form.Classification == types.FormSignup && field.Autocomplete == "current-password":
+				add(types.SeverityWarning, "autocomplete-misuse", "Signup password field %q should use autocomplete=new-password", field.Name)
+			case form.Classification == types.FormLogin && field.Autocomplete == "":
+				add(types.SeverityInfo, "autocomplete-misuse", "Login password field %q has no autocomplete=current-password hint", field.Name)
+			}
+		}
+		for _, field := range form.Fields {
+			if paymentFieldRegex.MatchString(field.Name) && field.Autocomplete == "on" {
+				add(types.SeverityInfo, "autocomplete-misuse", "Payment field %q uses autocomplete=on instead of a cc-* token", field.Name)
+			}
+		}
+	}
+}
+
+func formFieldsOfType(form types.FormInfo, fieldType string) []types.FormField {
+	var fields []types.FormField
+	for _, field := range form.Fields {
+		if field.Type == fieldType {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// registrableDomain returns the domain a host belongs to according to the public
+// suffix list, e.g. "www.example.co.uk" becomes "example.co.uk"
+func registrableDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_auditFormSecurity(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		html     string
+		severity string
+		check    string
+	}{
+		{
+			name:     "HTTP action from HTTPS page",
+			page:     "https://example.com/",
+			html:     `<form action="http://example.com/search"><input type="search" name="q"></form>`,
+			severity: types.SeverityError,
+			check:    "insecure-action",
+		},
+		{
+			name:     "Password on HTTP page",
+			page:     "http://example.com/",
+			html:     `<form method="post"><input name="user"><input type="password" name="pw"><input type="hidden" name="csrf"></form>`,
+			severity: types.SeverityError,
+			check:    "password-over-http",
+		},
+		{
+			name:     "Cross-domain action",
+			page:     "https://www.example.co.uk/",
+			html:     `<form action="https://collector.example.net/"><input name="email"></form>`,
+			severity: types.SeverityWarning,
+			check:    "cross-domain-action",
+		},
+		{
+			name:     "Password in GET form",
+			page:     "https://example.com/",
+			html:     `<form><input name="user"><input type="password" name="pw"></form>`,
+			severity: types.SeverityError,
+			check:    "password-in-get",
+		},
+		{
+			name:     "Login without CSRF token",
+			page:     "https://example.com/",
+			html:     `<form method="post"><input name="user"><input type="password" name="pw" autocomplete="current-password"></form>`,
+			severity: types.SeverityWarning,
+			check:    "missing-csrf",
+		},
+		{
+			name:     "Invalid password autocomplete",
+			page:     "https://example.com/",
+			html:     `<form method="post"><input name="user"><input type="password" name="pw" autocomplete="username"><input type="hidden" name="_token"></form>`,
+			severity: types.SeverityWarning,
+			check:    "autocomplete-misuse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			pageURL, _ := url.Parse(tt.page)
+			report := inventoryForms(doc, pageURL)
+
+			auditFormSecurity(report, pageURL)
+
+			assert.True(t, hasFinding(report.Findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, report.Findings)
+		})
+	}
+}
+
+func Test_auditFormSecurity_SameSiteSubdomain(t *testing.T) {
+	html := `<html><body><form method="post" action="https://login.example.co.uk/session">
+		<input type="hidden" name="authenticity_token"><input name="user">
+		<input type="password" name="pw" autocomplete="current-password">
+	</form></body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	pageURL, _ := url.Parse("https://www.example.co.uk/")
+	report := inventoryForms(doc, pageURL)
+
+	auditFormSecurity(report, pageURL)
+
+	assert.Empty(t, report.Findings)
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/html"
+)
+
+// ratioCheckMinBytes is the decoded size below which the decompression ratio is
+// not enforced, since small repetitive pages compress extremely well
+const ratioCheckMinBytes = 1 << 20
+
+// limits holds the analyzer limits; Configure replaces them at startup
+var limits = config.Analyzer{
+	MaxBodyBytes:          10 << 20,
+	MaxDecompressionRatio: 100,
+	MaxDOMNodes:           100000,
+	MaxLinks:              1000,
+	UserAgent:             "web-analyzer/1.0",
+	DefaultTimeout:        30 * time.Second,
+	MaxTimeout:            60 * time.Second,
+	MaxRedirects:          10,
+	MaxRequestHeaders:     20,
+}
+
+// Configure sets the limits and defaults applied to every analysis
+func Configure(cfg config.Analyzer) {
+	logrus.WithFields(logrus.Fields{
+		"maxBodyBytes":          cfg.MaxBodyBytes,
+		"maxDecompressionRatio": cfg.MaxDecompressionRatio,
+		"maxDOMNodes":           cfg.MaxDOMNodes,
+		"maxLinks":              cfg.MaxLinks,
+		"userAgent":             cfg.UserAgent,
+		"defaultTimeout":        cfg.DefaultTimeout,
+		"maxTimeout":            cfg.MaxTimeout,
+		"maxRedirects":          cfg.MaxRedirects,
+		"maxRequestHeaders":     cfg.MaxRequestHeaders,
+	}).Info("Analyzer limits configured")
+	limits = cfg
+}
+
+// limitedBody decodes the content encoding of a response body itself, so the
+// compressed size stays visible, and stops reading once the body exceeds the
+// size limit or inflates beyond the allowed ratio
+type limitedBody struct {
+	raw       *countingReader
+	decoded   io.Reader
+	body      io.Closer
+	read      int64
+	truncated string
+}
+
+// newLimitedBody wraps the body of a response requested with our own Accept-Encoding
+func newLimitedBody(resp *http.Response) (*limitedBody, error) {
+	b := &limitedBody{raw: &countingReader{reader: resp.Body}, body: resp.Body}
+	b.decoded = b.raw
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(b.raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response body: %w", err)
+		}
+		b.decoded = gz
+	case "deflate":
+		zr, err := zlib.NewReader(b.raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate response body: %w", err)
+		}
+		b.decoded = zr
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	return b, nil
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.truncated != "" {
+		return 0, io.EOF
+	}
+	if limits.MaxBodyBytes > 0 {
+		remaining := limits.MaxBodyBytes - b.read
+		if remaining <= 0 {
+			// A body of exactly the limit is not truncated
+			var probe [1]byte
+			if n, _ := b.decoded.Read(probe[:]); n > 0 {
+				b.truncate("the response body exceeds %d bytes", limits.MaxBodyBytes)
+			}
+			return 0, io.EOF
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := b.decoded.Read(p)
+	b.read += int64(n)
+	if limits.MaxDecompressionRatio > 0 && b.read > ratioCheckMinBytes && b.raw.count > 0 &&
+		float64(b.read)/float64(b.raw.count) > limits.MaxDecompressionRatio {
+		b.truncate("the response body inflates more than %g times", limits.MaxDecompressionRatio)
+	}
+	return n, err
+}
+
+func (b *limitedBody) truncate(format string, args ...interface{}) {
+	b.truncated = fmt.Sprintf(format, args...)
+	logrus.Warn("Stopped reading response body: ", b.truncated)
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// bodyTruncation returns why the body of the response was cut short, if it was
+func bodyTruncation(resp *http.Response) string {
+	if body, ok := resp.Body.(*limitedBody); ok {
+		return body.truncated
+	}
+	return ""
+}
+
+// markTruncated flags the result as covering only part of the target
+func markTruncated(result *types.AnalyzeResultes, format string, args ...interface{}) {
+	result.Truncated = true
+	result.TruncationReasons = append(result.TruncationReasons, fmt.Sprintf(format, args...))
+}
+
+// pruneDOM keeps the first MaxDOMNodes nodes of the tree in document order,
+// removes the rest and reports whether anything was removed
+func pruneDOM(root *html.Node) bool {
+	count, pruned := 0, false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if count >= limits.MaxDOMNodes {
+				n.RemoveChild(c)
+				pruned = true
+			} else {
+				count++
+				walk(c)
+			}
+			c = next
+		}
+	}
+	if limits.MaxDOMNodes > 0 {
+		walk(root)
+	}
+	return pruned
+}
+
+// checkLinks caps the links at the maximum of the analysis, checks the ones the
+// options select and counts them
+func checkLinks(result *types.AnalyzeResultes, links []types.LinkResult, opts *analysisOptions) {
+	if opts.maxLinks > 0 && len(links) > opts.maxLinks {
+		markTruncated(result, "only the first %d of %d links were considered", opts.maxLinks, len(links))
+		links = links[:opts.maxLinks]
+	}
+	result.Links = links
+	checkLinkStatuses(result.Links, opts)
+	countLinks(result)
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+)
+
+// withLimits replaces the analyzer limits for the duration of the test
+func withLimits(t *testing.T, cfg config.Analyzer) {
+	previous := limits
+	limits = cfg
+	t.Cleanup(func() { limits = previous })
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func Test_analyzePage_Limits(t *testing.T) {
+	page := "<html><head><title>Limits</title></head><body>" +
+		strings.Repeat(`<div><a href="/page">Page</a></div>`, 5) +
+		"<h2>Last</h2></body></html>"
+	bomb := gzipped(t, append([]byte("<html><head><title>Bomb</title></head><body>"), make([]byte, 20<<20)...))
+
+	tests := []struct {
+		name      string
+		limits    config.Analyzer
+		body      []byte
+		encoding  string
+		title     string
+		links     int
+		truncated string
+	}{
+		{name: "Within limits", limits: limits, body: []byte(page), title: "Limits", links: 5},
+		{name: "Gzip body is decoded", limits: limits, body: gzipped(t, []byte(page)), encoding: "gzip", title: "Limits", links: 5},
+		{name: "Body at the size limit", limits: config.Analyzer{MaxBodyBytes: int64(len(page))}, body: []byte(page), title: "Limits", links: 5},
+		{name: "Body over the size limit", limits: config.Analyzer{MaxBodyBytes: 100}, body: []byte(page), title: "Limits", links: 1, truncated: "exceeds 100 bytes"},
+		{name: "Decompression bomb", limits: config.Analyzer{MaxDecompressionRatio: 100}, body: bomb, encoding: "gzip", title: "Bomb", truncated: "inflates more than 100 times"},
+		{name: "Too many DOM nodes", limits: config.Analyzer{MaxDOMNodes: 12}, body: []byte(page), title: "Limits", links: 2, truncated: "first 12 DOM nodes"},
+		{name: "Too many links", limits: config.Analyzer{MaxLinks: 3}, body: []byte(page), title: "Limits", links: 3, truncated: "first 3 of 5 links"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withLimits(t, tt.limits)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer ts.Close()
+
+			result, err := analyzePage(ts.URL)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.title, result.Title)
+			assert.Len(t, result.Links, tt.links)
+			assert.Equal(t, tt.truncated != "", result.Truncated)
+			if tt.truncated != "" && assert.Len(t, result.TruncationReasons, 1) {
+				assert.Contains(t, result.TruncationReasons[0], tt.truncated)
+			}
+		})
+	}
+}
+
+func Test_Configure(t *testing.T) {
+	withLimits(t, limits)
+	cfg := config.Analyzer{MaxBodyBytes: 1, MaxDecompressionRatio: 2, MaxDOMNodes: 3, MaxLinks: 4}
+
+	Configure(cfg)
+
+	assert.Equal(t, cfg, limits)
+}
@@ -0,0 +1,318 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// loginRecipe is a config.Login with its URL canonicalized and its secret fields resolved
+type loginRecipe struct {
+	name         string
+	url          string
+	formSelector string
+	fields       map[string]string
+	// secretFields names the fields resolved from secret references
+	secretFields map[string]bool
+	success      config.LoginSuccess
+}
+
+var (
+	loginsMu     sync.RWMutex
+	loginRecipes = map[string]*loginRecipe{}
+)
+
+// LoginError is returned when the login a request names fails, in which case
+// the page is not analyzed
+type LoginError struct {
+	Name   string
+	Reason string
+	Err    error
+}
+
+func (e *LoginError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("login %q failed: %s: %v", e.Name, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("login %q failed: %s", e.Name, e.Reason)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigureLogins validates the configured logins and resolves their secret fields
+func ConfigureLogins(cfgs []config.Login) error {
+	recipes := make(map[string]*loginRecipe, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return fmt.Errorf("login for %s has no name", cfg.URL)
+		}
+		if recipes[cfg.Name] != nil {
+			return fmt.Errorf("login %q is defined twice", cfg.Name)
+		}
+		loginURL, err := normalizeURL(cfg.URL)
+		if err != nil {
+			return fmt.Errorf("login %q: invalid url: %w", cfg.Name, err)
+		}
+
+		recipe := &loginRecipe{
+			name:         cfg.Name,
+			url:          loginURL,
+			formSelector: cfg.FormSelector,
+			fields:       make(map[string]string),
+			secretFields: make(map[string]bool),
+			success:      cfg.Success,
+		}
+		for name, value := range cfg.Fields {
+			recipe.fields[name] = value
+		}
+		for name, ref := range cfg.SecretFields {
+			value, err := resolveSecret(ref)
+			if err != nil {
+				return fmt.Errorf("login %q: field %s: %w", cfg.Name, name, err)
+			}
+			recipe.fields[name] = value
+			recipe.secretFields[name] = true
+		}
+		recipes[cfg.Name] = recipe
+		logrus.WithFields(logrus.Fields{"name": cfg.Name, "url": loginURL}).Info("Login configured")
+	}
+
+	loginsMu.Lock()
+	loginRecipes = recipes
+	loginsMu.Unlock()
+	return nil
+}
+
+func loginRecipeNamed(name string) *loginRecipe {
+	loginsMu.RLock()
+	defer loginsMu.RUnlock()
+	return loginRecipes[name]
+}
+
+// performLogin fetches the login page, fills in and submits its login form and
+// checks that the login worked. The session ends up in the cookie jar of the
+// options, so the analysis that follows runs logged in.
+func performLogin(opts *analysisOptions) (*types.LoginReport, error) {
+	recipe := opts.login
+	logrus.Info("Logging in with ", recipe.name, " at ", recipe.url)
+	// The login has to follow redirects whatever the analysis does
+	loginOpts := *opts
+	loginOpts.followRedirects = true
+	fail := func(reason string, err error) (*types.LoginReport, error) {
+		logrus.Warn("Login ", recipe.name, " failed: ", reason)
+		// Errors of the client name the URL requested, which may carry field values
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, &LoginError{Name: recipe.name, Reason: reason, Err: err}
+	}
+
+	resp, err := fetchTimedURL(recipe.url, nil, &loginOpts)
+	if err != nil {
+		return fail("the login page could not be fetched", err)
+	}
+	formURL := resp.Request.URL
+	doc, err := readLoginPage(resp)
+	if err != nil {
+		return fail("the login page could not be read", err)
+	}
+
+	form := findLoginForm(doc, formURL, recipe.formSelector)
+	if form == nil {
+		if recipe.formSelector != "" {
+			return fail(fmt.Sprintf("no form matches %q on the login page", recipe.formSelector), nil)
+		}
+		return fail("no login form was found on the login page", nil)
+	}
+	req, err := buildLoginRequest(&loginOpts, form, formURL, recipe)
+	if err != nil {
+		return fail("the login form could not be submitted", err)
+	}
+
+	resp, err = loginOpts.client().Do(req)
+	if err != nil {
+		return fail("the login form could not be submitted", err)
+	}
+	limited, err := newLimitedBody(resp)
+	if err != nil {
+		resp.Body.Close()
+		return fail("the login response could not be read", err)
+	}
+	resp.Body = limited
+	doc, err = readLoginPage(resp)
+	if err != nil {
+		return fail("the login response could not be read", err)
+	}
+	if reason := checkLoginSuccess(recipe.success, resp, doc); reason != "" {
+		return fail(reason, nil)
+	}
+
+	logrus.Info("Logged in with ", recipe.name)
+	action := *req.URL
+	action.RawQuery = ""
+	return &types.LoginReport{
+		Name:     recipe.name,
+		FormURL:  formURL.String(),
+		Action:   action.String(),
+		Status:   resp.StatusCode,
+		FinalURL: resp.Request.URL.String(),
+	}, nil
+}
+
+func readLoginPage(resp *http.Response) (*goquery.Document, error) {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	body, _ = decodeBody(body, resp.Header.Get("Content-Type"))
+	return parseHTML(bytes.NewReader(body))
+}
+
+// findLoginForm returns the form matching selector or, without a selector, the
+// first form that form detection classifies as a login form
+func findLoginForm(doc *goquery.Document, pageURL *url.URL, selector string) *goquery.Selection {
+	if selector != "" {
+		form := doc.Find(selector).First()
+		if form.Length() == 0 || goquery.NodeName(form) != "form" {
+			return nil
+		}
+		return form
+	}
+	for _, info := range inventoryForms(doc, pageURL).Forms {
+		if !info.Implicit && info.Classification == types.FormLogin {
+			return doc.Find(info.Selector).First()
+		}
+	}
+	return nil
+}
+
+// buildLoginRequest submits the form the way a browser would, keeping hidden
+// fields such as CSRF tokens and the defaults of the other fields, with the
+// recipe's fields filled in on top. Forms submitted with GET are refused when
+// the recipe has secret fields, as they would end up in the URL.
+func buildLoginRequest(opts *analysisOptions, form *goquery.Selection, formURL *url.URL, recipe *loginRecipe) (*http.Request, error) {
+	values := url.Values{}
+	formFields(form).Each(func(i int, field *goquery.Selection) {
+		name, ok := field.Attr("name")
+		if _, disabled := field.Attr("disabled"); !ok || name == "" || disabled {
+			return
+		}
+		switch fieldType(field) {
+		case "submit", "button", "reset", "image", "file":
+		case "checkbox", "radio":
+			if _, checked := field.Attr("checked"); checked {
+				values.Add(name, field.AttrOr("value", "on"))
+			}
+		case "select":
+			option := field.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = field.Find("option").First()
+			}
+			if option.Length() > 0 {
+				values.Add(name, option.AttrOr("value", strings.TrimSpace(option.Text())))
+			}
+		case "textarea":
+			values.Add(name, field.Text())
+		default:
+			values.Add(name, field.AttrOr("value", ""))
+		}
+	})
+	for name, value := range recipe.fields {
+		values.Set(name, value)
+	}
+
+	action := resolveFormAction(form.AttrOr("action", ""), formURL)
+	var req *http.Request
+	var err error
+	if strings.EqualFold(strings.TrimSpace(form.AttrOr("method", "GET")), http.MethodPost) {
+		req, err = opts.newRequest(http.MethodPost, action, strings.NewReader(values.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else if len(recipe.secretFields) > 0 {
+		return nil, errors.New("the form is submitted with GET, which would put secret fields in the URL")
+	} else {
+		actionURL, parseErr := url.Parse(action)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		actionURL.RawQuery = values.Encode()
+		req, err = opts.newRequest(http.MethodGet, actionURL.String(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Sites commonly check these against cross-site form posts
+	req.Header.Set("Origin", formURL.Scheme+"://"+formURL.Host)
+	req.Header.Set("Referer", formURL.String())
+	return req, nil
+}
+
+// checkLoginSuccess returns why the login failed, or an empty string when it worked
+func checkLoginSuccess(success config.LoginSuccess, resp *http.Response, doc *goquery.Document) string {
+	finalURL := resp.Request.URL
+	checked := false
+	if success.Status != 0 {
+		checked = true
+		if resp.StatusCode != success.Status {
+			return fmt.Sprintf("the login response status was %d instead of %d", resp.StatusCode, success.Status)
+		}
+	}
+	if success.Redirect != "" {
+		checked = true
+		if !redirectMatches(success.Redirect, finalURL) {
+			return fmt.Sprintf("the login ended at %s instead of %s", finalURL.Path, success.Redirect)
+		}
+	}
+	if success.Selector != "" {
+		checked = true
+		if doc.Find(success.Selector).Length() == 0 {
+			return fmt.Sprintf("nothing matches %q on the page after login", success.Selector)
+		}
+	}
+	if checked {
+		return ""
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Sprintf("the login response status was %d", resp.StatusCode)
+	}
+	if hasLoginForm(doc) {
+		return "the page after login still shows a login form"
+	}
+	return ""
+}
+
+// redirectMatches reports whether the login ended at the expected URL, given
+// either as an absolute URL or as a path, and matched on whole path segments:
+// "/dash" matches "/dash" and "/dash/home" but not "/dashboard", and "/" only
+// matches the root
+func redirectMatches(expected string, finalURL *url.URL) bool {
+	want, err := url.Parse(expected)
+	if err != nil {
+		return false
+	}
+	if want.Host != "" && !strings.EqualFold(want.Host, finalURL.Host) {
+		return false
+	}
+	// Every path is under the root, so it only matches the root itself
+	if want.Path == "" || want.Path == "/" {
+		return finalURL.Path == "" || finalURL.Path == "/"
+	}
+	rest, ok := strings.CutPrefix(finalURL.Path, want.Path)
+	return ok && (rest == "" || strings.HasSuffix(want.Path, "/") || strings.HasPrefix(rest, "/"))
+}
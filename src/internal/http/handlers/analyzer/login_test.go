@@ -0,0 +1,237 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// withLogins replaces the configured logins for the duration of the test
+func withLogins(t *testing.T, cfgs []config.Login) {
+	loginsMu.RLock()
+	previous := loginRecipes
+	loginsMu.RUnlock()
+	assert.NoError(t, ConfigureLogins(cfgs))
+	t.Cleanup(func() {
+		loginsMu.Lock()
+		loginRecipes = previous
+		loginsMu.Unlock()
+	})
+}
+
+// newLoginServer serves a login form protected by a CSRF token and a members
+// page that needs the session cookie set by a successful login
+func newLoginServer(password string) *httptest.Server {
+	const csrfToken = "csrf-1234"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login" && r.Method == http.MethodGet:
+			w.Write([]byte(`<html><head><title>Sign in</title></head><body>
+				<form id="signin" method="post" action="/session">
+					<input type="hidden" name="csrf" value="` + csrfToken + `">
+					<input type="text" name="username">
+					<input type="password" name="password">
+					<input type="checkbox" name="remember" checked>
+					<button type="submit">Sign in</button>
+				</form></body></html>`))
+		case r.URL.Path == "/detached-login":
+			// The fields sit outside the form and join it through their form attribute
+			w.Write([]byte(`<html><body>
+				<input type="hidden" name="csrf" value="` + csrfToken + `" form="signin">
+				<input type="text" name="username" form="signin">
+				<input type="password" name="password" form="signin">
+				<input type="checkbox" name="remember" checked form="signin">
+				<form id="signin" method="post" action="/session"><button type="submit">Sign in</button></form>
+				<form id="search" action="/search"><input name="q" value="ignored"></form>
+				</body></html>`))
+		case r.URL.Path == "/get-login":
+			// Submitted with GET, to a port nothing listens on
+			w.Write([]byte(`<html><body><form action="http://127.0.0.1:1/session">
+				<input type="text" name="username"><input type="password" name="password">
+				<button type="submit">Log in</button></form></body></html>`))
+		case r.URL.Path == "/session" && r.Method == http.MethodPost:
+			if r.PostFormValue("csrf") != csrfToken || r.PostFormValue("username") != "analyzer" ||
+				r.PostFormValue("password") != password || r.PostFormValue("remember") != "on" {
+				http.Redirect(w, r, "/login?failed=1", http.StatusSeeOther)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "valid", Path: "/"})
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+		case r.URL.Path == "/account":
+			w.Write([]byte(`<html><body><a class="logout" href="/logout">Log out</a></body></html>`))
+		default:
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "valid" {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			w.Write([]byte(`<html><head><title>Members</title></head><body></body></html>`))
+		}
+	}))
+}
+
+func Test_ConfigureLogins_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfgs    []config.Login
+		wantErr string
+	}{
+		{name: "Missing name", cfgs: []config.Login{{URL: "https://example.com/login"}}, wantErr: "has no name"},
+		{name: "Duplicate name", cfgs: []config.Login{
+			{Name: "site", URL: "https://example.com/login"},
+			{Name: "site", URL: "https://example.org/login"},
+		}, wantErr: "defined twice"},
+		{name: "Invalid URL", cfgs: []config.Login{{Name: "site", URL: "ftp://example.com"}}, wantErr: "invalid url"},
+		{name: "Inline secret", cfgs: []config.Login{{
+			Name: "site", URL: "https://example.com/login", SecretFields: map[string]string{"password": "hunter2"},
+		}}, wantErr: "must be referenced"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfigureLogins(tt.cfgs)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.NotContains(t, err.Error(), "hunter2")
+		})
+	}
+}
+
+func Test_GetResults_Login(t *testing.T) {
+	const password = "s3cret-password"
+	t.Setenv("ANALYZER_TEST_PASSWORD", password)
+	ts := newLoginServer(password)
+	defer ts.Close()
+
+	withLogins(t, []config.Login{
+		{
+			Name:         "detected",
+			URL:          ts.URL + "/login",
+			Fields:       map[string]string{"username": "analyzer"},
+			SecretFields: map[string]string{"password": "env:ANALYZER_TEST_PASSWORD"},
+		},
+		{
+			Name:         "selector",
+			URL:          ts.URL + "/login",
+			FormSelector: "#signin",
+			Fields:       map[string]string{"username": "analyzer"},
+			SecretFields: map[string]string{"password": "env:ANALYZER_TEST_PASSWORD"},
+			Success:      config.LoginSuccess{Redirect: "/account", Selector: "a.logout"},
+		},
+		{
+			Name:   "wrong password",
+			URL:    ts.URL + "/login",
+			Fields: map[string]string{"username": "analyzer", "password": "wrong-password"},
+		},
+		{
+			Name:    "wrong selector",
+			URL:     ts.URL + "/login",
+			Fields:  map[string]string{"username": "analyzer"},
+			Success: config.LoginSuccess{Selector: "a.logout"},
+		},
+		{
+			Name:         "detached fields",
+			URL:          ts.URL + "/detached-login",
+			Fields:       map[string]string{"username": "analyzer"},
+			SecretFields: map[string]string{"password": "env:ANALYZER_TEST_PASSWORD"},
+		},
+		{
+			Name:         "get form",
+			URL:          ts.URL + "/get-login",
+			Fields:       map[string]string{"username": "analyzer"},
+			SecretFields: map[string]string{"password": "env:ANALYZER_TEST_PASSWORD"},
+		},
+		{
+			Name:   "unreachable action",
+			URL:    ts.URL + "/get-login",
+			Fields: map[string]string{"username": "analyzer", "password": "wrong-password"},
+		},
+		{
+			Name:         "missing form",
+			URL:          ts.URL + "/login",
+			FormSelector: "#register",
+		},
+	})
+
+	tests := []struct {
+		name       string
+		login      string
+		wantStatus int
+		want       string
+	}{
+		{name: "Detected form", login: "detected", wantStatus: http.StatusOK, want: "Members"},
+		{name: "Selector and success checks", login: "selector", wantStatus: http.StatusOK, want: "Members"},
+		{name: "Fields outside the form", login: "detached fields", wantStatus: http.StatusOK, want: "Members"},
+		{name: "Wrong password", login: "wrong password", wantStatus: http.StatusBadGateway, want: "still shows a login form"},
+		{name: "Failed success check", login: "wrong selector", wantStatus: http.StatusBadGateway, want: "nothing matches"},
+		{name: "Secret fields over GET", login: "get form", wantStatus: http.StatusBadGateway, want: "submitted with GET"},
+		{name: "Unreachable action", login: "unreachable action", wantStatus: http.StatusBadGateway, want: "could not be submitted"},
+		{name: "Missing form", login: "missing form", wantStatus: http.StatusBadGateway, want: "no form matches"},
+		{name: "Unknown login", login: "unknown", wantStatus: http.StatusBadRequest, want: `unknown login`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := test.NewLocal(logrus.StandardLogger())
+			defer hook.Reset()
+			body, _ := json.Marshal(types.RequestPayload{URL: ts.URL, Login: tt.login})
+			req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			GetResults(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.want)
+			if tt.wantStatus == http.StatusOK {
+				var result types.AnalyzeResultes
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				if assert.NotNil(t, result.Login) {
+					assert.Equal(t, tt.login, result.Login.Name)
+					assert.Equal(t, ts.URL+"/session", result.Login.Action)
+					assert.Equal(t, ts.URL+"/account", result.Login.FinalURL)
+				}
+			}
+			// Field values are never echoed
+			assert.NotContains(t, rec.Body.String(), password)
+			assert.NotContains(t, rec.Body.String(), "wrong-password")
+			for _, entry := range hook.AllEntries() {
+				line, _ := entry.String()
+				assert.NotContains(t, line, password)
+				assert.NotContains(t, line, "wrong-password")
+			}
+		})
+	}
+}
+
+func Test_redirectMatches(t *testing.T) {
+	tests := []struct {
+		expected string
+		final    string
+		want     bool
+	}{
+		{expected: "/dash", final: "https://example.com/dash", want: true},
+		{expected: "/dash", final: "https://example.com/dash/home", want: true},
+		{expected: "/dash", final: "https://example.com/dashboard-login-failed", want: false},
+		{expected: "/dash/", final: "https://example.com/dash/home", want: true},
+		{expected: "/dash/", final: "https://example.com/dash", want: false},
+		{expected: "/", final: "https://example.com/", want: true},
+		{expected: "/", final: "https://example.com/login?failed=1", want: false},
+		{expected: "https://example.com/account", final: "https://example.com/account", want: true},
+		{expected: "https://example.com/account", final: "https://other.example/account", want: false},
+		{expected: "https://example.com", final: "https://example.com/", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected+" "+tt.final, func(t *testing.T) {
+			finalURL, _ := url.Parse(tt.final)
+			assert.Equal(t, tt.want, redirectMatches(tt.expected, finalURL))
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+var (
+	cssURLRegex    = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]+)`)
+	cssImportRegex = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)`)
+)
+
+// resourceAttributes lists, per element, the attributes holding URLs the browser loads
+var resourceAttributes = map[string][]string{
+	"script": {"src"},
+	"link":   {"href"},
+	"iframe": {"src"},
+	"frame":  {"src"},
+	"embed":  {"src"},
+	"object": {"data"},
+	"img":    {"src", "srcset"},
+	"source": {"src", "srcset"},
+	"audio":  {"src"},
+	"video":  {"src", "poster"},
+	"track":  {"src"},
+	"input":  {"src"},
+	"form":   {"action"},
+}
+
+// linkResourceRels are the <link rel> values that make the browser fetch the target
+var linkResourceRels = toSet("stylesheet", "icon", "apple-touch-icon", "mask-icon", "preload", "modulepreload", "prefetch", "manifest")
+
+// resourceRef is a URL that an element makes the browser load
+type resourceRef struct {
+	selection *goquery.Selection
+	element   string
+	// attribute is empty for URLs found in the text of a <style> element
+	attribute string
+	url       *url.URL
+	// cssImport is set for stylesheets pulled in with @import
+	cssImport bool
+}
+
+// collectResourceRefs lists every subresource URL in the page resolved against
+// the document base, including srcset candidates and CSS url() references
+func collectResourceRefs(doc *goquery.Document, pageURL *url.URL) []resourceRef {
+	base := documentBase(doc, pageURL)
+	var refs []resourceRef
+	add := func(s *goquery.Selection, attribute, raw string, cssImport bool) {
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || raw == "" {
+			return
+		}
+		refs = append(refs, resourceRef{
+			selection: s,
+			element:   goquery.NodeName(s),
+			attribute: attribute,
+			url:       base.ResolveReference(parsed),
+			cssImport: cssImport,
+		})
+	}
+
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		element := goquery.NodeName(s)
+		if element == "link" && !loadsResource(s) {
+			return
+		}
+		for _, attribute := range resourceAttributes[element] {
+			value, ok := s.Attr(attribute)
+			if !ok {
+				continue
+			}
+			if attribute == "srcset" {
+				for _, candidate := range parseSrcset(value) {
+					add(s, attribute, candidate, false)
+				}
+				continue
+			}
+			add(s, attribute, value, false)
+		}
+
+		if style, ok := s.Attr("style"); ok {
+			for _, match := range cssURLRegex.FindAllStringSubmatch(style, -1) {
+				add(s, "style", match[1], false)
+			}
+		}
+		if element == "style" {
+			imports := make(map[string]bool)
+			for _, match := range cssImportRegex.FindAllStringSubmatch(s.Text(), -1) {
+				imports[match[1]] = true
+				add(s, "", match[1], true)
+			}
+			for _, match := range cssURLRegex.FindAllStringSubmatch(s.Text(), -1) {
+				if !imports[match[1]] {
+					add(s, "", match[1], false)
+				}
+			}
+		}
+	})
+	return refs
+}
+
+// documentBase returns the URL relative references resolve against, honouring <base href>
+func documentBase(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	base := pageURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if parsed, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = pageURL.ResolveReference(parsed)
+		}
+	}
+	return base
+}
+
+func loadsResource(link *goquery.Selection) bool {
+	for _, rel := range strings.Fields(strings.ToLower(link.AttrOr("rel", ""))) {
+		if linkResourceRels[rel] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSrcset returns the URLs of the candidates in a srcset attribute. It
+// follows the HTML parsing rules: a URL runs up to whitespace, so it may contain
+// commas (data: URIs do), and a trailing comma ends the candidate without
+// descriptors. Otherwise the descriptors run to the next comma outside parens.
+func parseSrcset(srcset string) []string {
+	var urls []string
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r' }
+	for pos := 0; pos < len(srcset); {
+		for pos < len(srcset) && (isSpace(srcset[pos]) || srcset[pos] == ',') {
+			pos++
+		}
+		start := pos
+		for pos < len(srcset) && !isSpace(srcset[pos]) {
+			pos++
+		}
+		candidate := srcset[start:pos]
+		if candidate == "" {
+			break
+		}
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			// The comma ends the candidate, no descriptors follow
+			if trimmed != "" {
+				urls = append(urls, trimmed)
+			}
+			continue
+		}
+		urls = append(urls, candidate)
+		for depth := 0; pos < len(srcset); pos++ {
+			if c := srcset[pos]; c == '(' {
+				depth++
+			} else if c == ')' && depth > 0 {
+				depth--
+			} else if c == ',' && depth == 0 {
+				break
+			}
+		}
+	}
+	return urls
+}
+
+// detectMixedContent reports the subresources an HTTPS page loads over plain
+// HTTP; it returns nil for pages that are not served over HTTPS
+func detectMixedContent(doc *goquery.Document, header http.Header, pageURL *url.URL) *types.MixedContentReport {
+	if pageURL == nil || pageURL.Scheme != "https" {
+		return nil
+	}
+	logrus.Debug("Detecting mixed content")
+	report := &types.MixedContentReport{Items: []types.MixedContentItem{}, Findings: []types.Finding{}}
+	for _, policy := range collectCSP(doc, header) {
+		if _, ok := policy.Directives["upgrade-insecure-requests"]; ok && !policy.ReportOnly {
+			report.UpgradeInsecureRequests = true
+		}
+	}
+
+	for _, ref := range collectResourceRefs(doc, pageURL) {
+		if ref.url.Scheme != "http" {
+			continue
+		}
+		item := types.MixedContentItem{
+			URL:       ref.url.String(),
+			Element:   ref.element,
+			Attribute: ref.attribute,
+			Category:  mixedContentCategory(ref),
+			Selector:  cssPath(ref.selection),
+		}
+		report.Items = append(report.Items, item)
+		addMixedContentFinding(report, item)
+	}
+
+	logrus.Debug("Found ", len(report.Items), " mixed content references")
+	return report
+}
+
+// mixedContentCategory classifies a reference following the mixed content spec:
+// images, audio and video are passive, everything else can alter the page and is active
+func mixedContentCategory(ref resourceRef) string {
+	switch ref.element {
+	case "form":
+		return types.MixedContentForm
+	case "img", "audio", "video", "source", "input":
+		return types.MixedContentPassive
+	case "link":
+		rel := strings.ToLower(ref.selection.AttrOr("rel", ""))
+		if strings.Contains(rel, "icon") {
+			return types.MixedContentPassive
+		}
+	case "style":
+		if !ref.cssImport {
+			return types.MixedContentPassive
+		}
+	}
+	if ref.attribute == "style" {
+		return types.MixedContentPassive
+	}
+	return types.MixedContentActive
+}
+
+func addMixedContentFinding(report *types.MixedContentReport, item types.MixedContentItem) {
+	verb := "loads"
+	switch item.Category {
+	case types.MixedContentActive:
+		report.Active++
+	case types.MixedContentForm:
+		report.Forms++
+		verb = "submits to"
+	default:
+		report.Passive++
+	}
+
+	finding := types.Finding{Selector: item.Selector}
+	switch {
+	case report.UpgradeInsecureRequests:
+		finding.Severity, finding.Check = types.SeverityInfo, "mixed-content-upgraded"
+		finding.Message = fmt.Sprintf("<%s> %s %s over HTTP, upgraded by upgrade-insecure-requests", item.Element, verb, item.URL)
+	case item.Category == types.MixedContentActive:
+		finding.Severity, finding.Check = types.SeverityError, "mixed-content-active"
+		finding.Message = fmt.Sprintf("<%s> loads %s over HTTP, browsers block active mixed content", item.Element, item.URL)
+	case item.Category == types.MixedContentForm:
+		finding.Severity, finding.Check = types.SeverityWarning, "mixed-content-form"
+		finding.Message = fmt.Sprintf("<%s> submits to %s over HTTP, browsers warn before sending it and the data is not encrypted", item.Element, item.URL)
+	default:
+		finding.Severity, finding.Check = types.SeverityWarning, "mixed-content-passive"
+		finding.Message = fmt.Sprintf("<%s> loads %s over HTTP", item.Element, item.URL)
+	}
+	report.Findings = append(report.Findings, finding)
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_detectMixedContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		url       string
+		category  string
+		attribute string
+		found     string
+	}{
+		{name: "Script", html: `<script src="http://cdn.example.com/app.js"></script>`, category: types.MixedContentActive, attribute: "src", found: "http://cdn.example.com/app.js"},
+		{name: "Stylesheet", html: `<link rel="stylesheet" href="http://cdn.example.com/site.css">`, category: types.MixedContentActive, attribute: "href", found: "http://cdn.example.com/site.css"},
+		{name: "Iframe", html: `<iframe src="http://widgets.example.com/"></iframe>`, category: types.MixedContentActive, attribute: "src", found: "http://widgets.example.com/"},
+		{name: "Form action", html: `<form action="http://example.com/login"></form>`, category: types.MixedContentForm, attribute: "action", found: "http://example.com/login"},
+		{name: "Image", html: `<img src="http://img.example.com/a.png">`, category: types.MixedContentPassive, attribute: "src", found: "http://img.example.com/a.png"},
+		{name: "Srcset candidate", html: `<img src="/a.png" srcset="/a.png 1x, http://img.example.com/a@2x.png 2x">`, category: types.MixedContentPassive, attribute: "srcset", found: "http://img.example.com/a@2x.png"},
+		{name: "Video poster", html: `<video poster="http://img.example.com/poster.jpg"></video>`, category: types.MixedContentPassive, attribute: "poster", found: "http://img.example.com/poster.jpg"},
+		{name: "Favicon", html: `<link rel="icon" href="http://example.com/favicon.ico">`, category: types.MixedContentPassive, attribute: "href", found: "http://example.com/favicon.ico"},
+		{name: "Inline style", html: `<div style="background: url('http://img.example.com/bg.png')"></div>`, category: types.MixedContentPassive, attribute: "style", found: "http://img.example.com/bg.png"},
+		{name: "Style import", html: `<style>@import "http://cdn.example.com/extra.css";</style>`, category: types.MixedContentActive, attribute: "", found: "http://cdn.example.com/extra.css"},
+		{name: "Insecure base", html: `<base href="http://example.com/"><script src="app.js"></script>`, category: types.MixedContentActive, attribute: "src", found: "http://example.com/app.js"},
+		{name: "Protocol-relative", html: `<script src="//cdn.example.com/app.js"></script>`},
+		{name: "Navigation link", html: `<a href="http://example.com/">Home</a><link rel="canonical" href="http://example.com/">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			pageURL, _ := url.Parse("https://example.com/page")
+
+			got := detectMixedContent(doc, http.Header{}, pageURL)
+
+			if tt.found == "" {
+				assert.Empty(t, got.Items)
+				return
+			}
+			if assert.Len(t, got.Items, 1) {
+				assert.Equal(t, tt.found, got.Items[0].URL)
+				assert.Equal(t, tt.category, got.Items[0].Category)
+				assert.Equal(t, tt.attribute, got.Items[0].Attribute)
+				assert.NotEmpty(t, got.Items[0].Selector)
+			}
+		})
+	}
+}
+
+func Test_detectMixedContent_Findings(t *testing.T) {
+	page := `<html><body><script src="http://cdn.example.com/app.js"></script><img src="http://img.example.com/a.png">
+		<form action="http://example.com/login"></form></body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(page))
+	pageURL, _ := url.Parse("https://example.com/")
+
+	got := detectMixedContent(doc, http.Header{}, pageURL)
+	assert.Equal(t, 1, got.Active)
+	assert.Equal(t, 1, got.Passive)
+	assert.Equal(t, 1, got.Forms)
+	assert.True(t, hasFinding(got.Findings, types.SeverityError, "mixed-content-active"))
+	assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "mixed-content-passive"))
+	if assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "mixed-content-form")) {
+		assert.NotContains(t, got.Findings[2].Message, "block")
+		assert.Contains(t, got.Findings[2].Message, "submits to http://example.com/login")
+	}
+	assert.Equal(t, "html > body > script", got.Findings[0].Selector)
+
+	upgraded := detectMixedContent(doc, http.Header{"Content-Security-Policy": {"upgrade-insecure-requests"}}, pageURL)
+	assert.True(t, upgraded.UpgradeInsecureRequests)
+	assert.True(t, hasFinding(upgraded.Findings, types.SeverityInfo, "mixed-content-upgraded"))
+	assert.False(t, hasFinding(upgraded.Findings, types.SeverityError, "mixed-content-active"))
+}
+
+func Test_detectMixedContent_PlainHTTP(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<img src="http://img.example.com/a.png">`))
+	pageURL, _ := url.Parse("http://example.com/")
+
+	assert.Nil(t, detectMixedContent(doc, http.Header{}, pageURL))
+}
+
+func Test_parseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []string
+	}{
+		{name: "Descriptors", srcset: "/a.png 1x, /a@2x.png 2x", want: []string{"/a.png", "/a@2x.png"}},
+		{name: "Comma in URL", srcset: "/img?size=1,2 1x, /b.png 2x", want: []string{"/img?size=1,2", "/b.png"}},
+		{name: "Data URI", srcset: "data:image/png;base64,iVBORw0KGgo= 1x, http://img.example.com/a.png 2x", want: []string{"data:image/png;base64,iVBORw0KGgo=", "http://img.example.com/a.png"}},
+		{name: "Trailing comma ends candidate", srcset: "/a.png, /b.png, /c.png 2x,", want: []string{"/a.png", "/b.png", "/c.png"}},
+		{name: "No spaces between candidates", srcset: "/a.png 1x,/b.png 2x", want: []string{"/a.png", "/b.png"}},
+		{name: "Comma without space", srcset: "/a.png,/b.png", want: []string{"/a.png,/b.png"}},
+		{name: "Empty", srcset: " , ", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseSrcset(tt.srcset))
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"golang.org/x/net/proxy"
+)
+
+// network is a config.Network with its addresses parsed and its proxy password resolved
+type network struct {
+	proxy *url.URL
+	// proxyAddr is the host:port the transport dials for an HTTP proxy
+	proxyAddr    string
+	noProxyHosts []string
+	noProxyCIDRs []netip.Prefix
+	resolver     *net.Resolver
+	hosts        map[string]netip.Addr
+}
+
+var (
+	networkMu sync.RWMutex
+	outbound  = &network{resolver: net.DefaultResolver}
+)
+
+// ConfigureNetwork sets the proxy, resolver and static host addresses used for
+// fetching pages and checking links
+func ConfigureNetwork(cfg config.Network) error {
+	n := &network{resolver: net.DefaultResolver, hosts: make(map[string]netip.Addr)}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		if proxyURL.User != nil {
+			return errors.New("the proxy URL must not contain credentials, use proxy_username and proxy_password")
+		}
+		port := proxyURL.Port()
+		switch proxyURL.Scheme {
+		case "http", "https":
+			if port == "" {
+				port = defaultPorts[proxyURL.Scheme]
+			}
+		case "socks5":
+			if port == "" {
+				port = "1080"
+			}
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+		}
+		if proxyURL.Hostname() == "" {
+			return errors.New("the proxy URL has no host")
+		}
+		if cfg.ProxyUsername != "" {
+			password, err := resolveSecret(cfg.ProxyPassword)
+			if err != nil {
+				return fmt.Errorf("proxy_password: %w", err)
+			}
+			proxyURL.User = url.UserPassword(cfg.ProxyUsername, password)
+		}
+		n.proxy = proxyURL
+		n.proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	for _, entry := range cfg.NoProxy {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return fmt.Errorf("invalid no_proxy entry %q: %w", entry, err)
+			}
+			n.noProxyCIDRs = append(n.noProxyCIDRs, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+			n.noProxyCIDRs = append(n.noProxyCIDRs, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			n.noProxyHosts = append(n.noProxyHosts, normalizeHostPatterns([]string{entry})...)
+		}
+	}
+
+	if cfg.Resolver != "" {
+		server := cfg.Resolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		if _, err := netip.ParseAddrPort(server); err != nil {
+			return fmt.Errorf("invalid resolver %q, use an IP address and optional port", cfg.Resolver)
+		}
+		n.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	for host, address := range cfg.Hosts {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			return fmt.Errorf("invalid address %q for host %s", address, host)
+		}
+		n.hosts[strings.ToLower(strings.TrimSuffix(host, "."))] = addr.Unmap()
+	}
+
+	networkMu.Lock()
+	outbound = n
+	networkMu.Unlock()
+	// Pooled connections were made through the previous proxy and addresses
+	httpClient.CloseIdleConnections()
+
+	fields := logrus.Fields{"noProxy": cfg.NoProxy, "resolver": cfg.Resolver, "hosts": cfg.Hosts}
+	if n.proxy != nil {
+		fields["proxy"] = n.proxy.Redacted()
+	}
+	logrus.WithFields(fields).Info("Network configured")
+	return nil
+}
+
+func currentNetwork() *network {
+	networkMu.RLock()
+	defer networkMu.RUnlock()
+	return outbound
+}
+
+// proxyFor returns the proxy to reach host through, or nil to connect directly
+func (n *network) proxyFor(host string) *url.URL {
+	if n.proxy == nil {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchesHostPattern(host, n.noProxyHosts) {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && containsAddr(n.noProxyCIDRs, addr.Unmap()) {
+		return nil
+	}
+	return n.proxy
+}
+
+// lookup resolves host with the configured static addresses and resolver
+func (n *network) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	if addr, ok := n.hosts[host]; ok {
+		return []netip.Addr{addr}, nil
+	}
+	return n.resolver.LookupNetIP(ctx, "ip", host)
+}
+
+// proxyForRequest is the transport's proxy function for HTTP proxies. Such a
+// proxy resolves and connects to the target itself, so hosts with a static
+// address are connected to directly instead, and other targets are checked
+// against the SSRF guard before the request is handed over. The proxy resolves
+// the name again, so it has to enforce its own egress policy as well.
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	n := currentNetwork()
+	host := strings.ToLower(strings.TrimSuffix(req.URL.Hostname(), "."))
+	proxyURL := n.proxyFor(host)
+	if proxyURL == nil || proxyURL.Scheme == "socks5" {
+		return nil, nil
+	}
+	if _, ok := n.hosts[host]; ok {
+		return nil, nil
+	}
+
+	g := currentGuard()
+	if matchesHostPattern(host, g.denyHosts) {
+		return nil, &BlockedTargetError{Host: host, Reason: "host is denied"}
+	}
+	addrs, err := n.lookup(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+	hostAllowed := matchesHostPattern(host, g.allowHosts)
+	for _, addr := range addrs {
+		// The proxy may connect to any of the addresses, so all of them must pass
+		if err := g.checkAddr(host, addr, hostAllowed); err != nil {
+			return nil, err
+		}
+	}
+	return proxyURL, nil
+}
+
+// dialSOCKS connects to addr through a SOCKS5 proxy. The analyzer resolves the
+// target itself and hands the proxy an address that passed the SSRF guard, so
+// the configured resolver and static addresses apply through the proxy too.
+func dialSOCKS(ctx context.Context, n *network, g *ssrfGuard, network, host, port string, hostAllowed bool) (net.Conn, error) {
+	addrs, err := n.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var auth *proxy.Auth
+	if n.proxy.User != nil {
+		password, _ := n.proxy.User.Password()
+		auth = &proxy.Auth{User: n.proxy.User.Username(), Password: password}
+	}
+	forward := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: n.resolver}
+	dialer, err := proxy.SOCKS5("tcp", n.proxyAddr, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, addr := range addrs {
+		err := g.checkAddr(host, addr, hostAllowed)
+		if err == nil {
+			var conn net.Conn
+			if conn, err = dialer.(proxy.ContextDialer).DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
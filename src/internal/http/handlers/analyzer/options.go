@@ -0,0 +1,212 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vinothnada/web-analyzer/internal/types"
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/publicsuffix"
+)
+
+// availableChecks are the checks a request can select; the doctype, title and
+// encoding are always reported
+var availableChecks = toSet("headings", "links", "forms", "seo", "csp", "mixedContent", "resources",
+	"structuredData", "accessibility", "securityHeaders", "cookies", "tls")
+
+// restrictedHeaders are managed by the analyzer or the transport and cannot be set by a request
+var restrictedHeaders = toSet("host", "content-length", "transfer-encoding", "connection", "keep-alive",
+	"upgrade", "te", "trailer", "proxy-authorization", "proxy-connection", "accept-encoding")
+
+// analysisOptions are the validated settings of a single analysis
+type analysisOptions struct {
+	pageURL            *url.URL
+	userAgent          string
+	acceptLanguage     string
+	headers            http.Header
+	timeout            time.Duration
+	checkExternalLinks bool
+	checkInternalLinks bool
+	maxLinks           int
+	// checks is nil when every check runs
+	checks          map[string]bool
+	followRedirects bool
+	// jar is nil unless the request asks for a cookie jar or a login
+	jar   http.CookieJar
+	login *loginRecipe
+}
+
+// defaultOptions returns the configured defaults for analyzing targetURL
+func defaultOptions(targetURL string) *analysisOptions {
+	pageURL, _ := url.Parse(targetURL)
+	return &analysisOptions{
+		pageURL:            pageURL,
+		userAgent:          limits.UserAgent,
+		headers:            make(http.Header),
+		timeout:            limits.DefaultTimeout,
+		checkExternalLinks: true,
+		maxLinks:           limits.MaxLinks,
+		followRedirects:    true,
+	}
+}
+
+// resolveOptions validates the options of the payload against the configured
+// caps and fills in the defaults for the ones it leaves unset
+func resolveOptions(payload types.RequestPayload) (*analysisOptions, error) {
+	opts := defaultOptions(payload.URL)
+	if payload.UserAgent != "" {
+		if !httpguts.ValidHeaderFieldValue(payload.UserAgent) {
+			return nil, fmt.Errorf("invalid userAgent %q", payload.UserAgent)
+		}
+		opts.userAgent = payload.UserAgent
+	}
+	if payload.AcceptLanguage != "" {
+		if !httpguts.ValidHeaderFieldValue(payload.AcceptLanguage) {
+			return nil, fmt.Errorf("invalid acceptLanguage %q", payload.AcceptLanguage)
+		}
+		opts.acceptLanguage = payload.AcceptLanguage
+	}
+
+	if limits.MaxRequestHeaders > 0 && len(payload.Headers) > limits.MaxRequestHeaders {
+		return nil, fmt.Errorf("at most %d headers may be set", limits.MaxRequestHeaders)
+	}
+	for name, value := range payload.Headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid header %q", name)
+		}
+		if restrictedHeaders[strings.ToLower(name)] {
+			return nil, fmt.Errorf("header %q cannot be set", name)
+		}
+		opts.headers.Set(name, value)
+	}
+
+	if payload.TimeoutSeconds < 0 {
+		return nil, fmt.Errorf("timeoutSeconds must be positive")
+	}
+	if payload.TimeoutSeconds > 0 {
+		opts.timeout = time.Duration(payload.TimeoutSeconds * float64(time.Second))
+		if limits.MaxTimeout > 0 && opts.timeout > limits.MaxTimeout {
+			return nil, fmt.Errorf("timeoutSeconds may not exceed %g", limits.MaxTimeout.Seconds())
+		}
+	}
+
+	if payload.MaxLinks < 0 {
+		return nil, fmt.Errorf("maxLinks must be positive")
+	}
+	if payload.MaxLinks > 0 {
+		if limits.MaxLinks > 0 && payload.MaxLinks > limits.MaxLinks {
+			return nil, fmt.Errorf("maxLinks may not exceed %d", limits.MaxLinks)
+		}
+		opts.maxLinks = payload.MaxLinks
+	}
+	if payload.CheckExternalLinks != nil {
+		opts.checkExternalLinks = *payload.CheckExternalLinks
+	}
+	opts.checkInternalLinks = payload.CheckInternalLinks
+
+	if len(payload.Checks) > 0 {
+		opts.checks = make(map[string]bool)
+		for _, check := range payload.Checks {
+			if !availableChecks[check] {
+				return nil, fmt.Errorf("unknown check %q", check)
+			}
+			opts.checks[check] = true
+		}
+	}
+	if payload.FollowRedirects != nil {
+		opts.followRedirects = *payload.FollowRedirects
+	}
+
+	if payload.Login != "" {
+		if opts.login = loginRecipeNamed(payload.Login); opts.login == nil {
+			return nil, fmt.Errorf("unknown login %q", payload.Login)
+		}
+	}
+	if payload.CookieJar || len(payload.Cookies) > 0 || opts.login != nil {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, err
+		}
+		var cookies []*http.Cookie
+		for name, value := range payload.Cookies {
+			cookie := &http.Cookie{Name: name, Value: value}
+			// The value is left out of the error so it is never echoed
+			if err := cookie.Valid(); err != nil {
+				return nil, fmt.Errorf("invalid cookie %q", name)
+			}
+			cookies = append(cookies, cookie)
+		}
+		jar.SetCookies(opts.pageURL, cookies)
+		opts.jar = jar
+	}
+	return opts, nil
+}
+
+// enabled reports whether the named check runs
+func (o *analysisOptions) enabled(check string) bool {
+	return o.checks == nil || o.checks[check]
+}
+
+// client returns httpClient with the timeout and redirect policy of the analysis
+func (o *analysisOptions) client() *http.Client {
+	client := *httpClient
+	client.Timeout = o.timeout
+	client.Jar = o.jar
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !o.followRedirects {
+			return http.ErrUseLastResponse
+		}
+		if limits.MaxRedirects > 0 && len(via) >= limits.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", limits.MaxRedirects)
+		}
+		// Headers copied from the first request must not leak to other hosts
+		if !o.isTargetHost(req.URL) {
+			for name := range o.headers {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}
+	return &client
+}
+
+// newRequest builds a request carrying the user agent and language of the
+// analysis, and its extra headers when the request goes to the target's host
+func (o *analysisOptions) newRequest(method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if o.userAgent != "" {
+		req.Header.Set("User-Agent", o.userAgent)
+	}
+	if o.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", o.acceptLanguage)
+	}
+	if o.isTargetHost(req.URL) {
+		for name, values := range o.headers {
+			req.Header[name] = values
+		}
+	}
+	return req, nil
+}
+
+// isTargetHost reports whether u points at the same host and port as the analyzed page
+func (o *analysisOptions) isTargetHost(u *url.URL) bool {
+	if o.pageURL == nil {
+		return false
+	}
+	hostPort := func(u *url.URL) string {
+		port := u.Port()
+		if port == "" {
+			port = defaultPorts[strings.ToLower(u.Scheme)]
+		}
+		return strings.ToLower(strings.TrimSuffix(u.Hostname(), ".")) + ":" + port
+	}
+	return hostPort(u) == hostPort(o.pageURL)
+}
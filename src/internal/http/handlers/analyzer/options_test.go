@@ -0,0 +1,193 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_resolveOptions(t *testing.T) {
+	withLimits(t, limits)
+	disabled := false
+	headers := make(map[string]string)
+	for i := 0; i <= limits.MaxRequestHeaders; i++ {
+		headers[strings.Repeat("X", i+1)] = "1"
+	}
+
+	tests := []struct {
+		name    string
+		payload types.RequestPayload
+		check   func(t *testing.T, opts *analysisOptions)
+		wantErr string
+	}{
+		{name: "Defaults", payload: types.RequestPayload{}, check: func(t *testing.T, opts *analysisOptions) {
+			assert.Equal(t, limits.UserAgent, opts.userAgent)
+			assert.Equal(t, limits.DefaultTimeout, opts.timeout)
+			assert.Equal(t, limits.MaxLinks, opts.maxLinks)
+			assert.True(t, opts.checkExternalLinks)
+			assert.False(t, opts.checkInternalLinks)
+			assert.True(t, opts.followRedirects)
+			assert.True(t, opts.enabled("seo"))
+		}},
+		{name: "Every option", payload: types.RequestPayload{
+			UserAgent:          "team-bot/2.0",
+			AcceptLanguage:     "de-DE, en;q=0.5",
+			Headers:            map[string]string{"x-team": "search"},
+			TimeoutSeconds:     2.5,
+			CheckExternalLinks: &disabled,
+			CheckInternalLinks: true,
+			MaxLinks:           5,
+			Checks:             []string{"seo", "links"},
+			FollowRedirects:    &disabled,
+		}, check: func(t *testing.T, opts *analysisOptions) {
+			assert.Equal(t, "team-bot/2.0", opts.userAgent)
+			assert.Equal(t, "de-DE, en;q=0.5", opts.acceptLanguage)
+			assert.Equal(t, "search", opts.headers.Get("X-Team"))
+			assert.Equal(t, 2500*time.Millisecond, opts.timeout)
+			assert.False(t, opts.checkExternalLinks)
+			assert.True(t, opts.checkInternalLinks)
+			assert.Equal(t, 5, opts.maxLinks)
+			assert.True(t, opts.enabled("links"))
+			assert.False(t, opts.enabled("accessibility"))
+			assert.False(t, opts.followRedirects)
+		}},
+		{name: "Invalid user agent", payload: types.RequestPayload{UserAgent: "bot\r\nX-Injected: 1"}, wantErr: "invalid userAgent"},
+		{name: "Invalid header name", payload: types.RequestPayload{Headers: map[string]string{"X Team": "1"}}, wantErr: "invalid header"},
+		{name: "Restricted header", payload: types.RequestPayload{Headers: map[string]string{"Host": "internal"}}, wantErr: "cannot be set"},
+		{name: "Too many headers", payload: types.RequestPayload{Headers: headers}, wantErr: "at most 20 headers"},
+		{name: "Timeout over the cap", payload: types.RequestPayload{TimeoutSeconds: 61}, wantErr: "may not exceed 60"},
+		{name: "Negative timeout", payload: types.RequestPayload{TimeoutSeconds: -1}, wantErr: "must be positive"},
+		{name: "Max links over the cap", payload: types.RequestPayload{MaxLinks: 1001}, wantErr: "may not exceed 1000"},
+		{name: "Unknown check", payload: types.RequestPayload{Checks: []string{"seo", "spelling"}}, wantErr: `unknown check "spelling"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payload.URL = "https://example.com/"
+			opts, err := resolveOptions(tt.payload)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.check(t, opts)
+		})
+	}
+}
+
+func Test_GetResults_Options(t *testing.T) {
+	var mu sync.Mutex
+	var linkRequests []*http.Request
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		linkRequests = append(linkRequests, r)
+		mu.Unlock()
+	}))
+	defer other.Close()
+
+	var pageRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			pageRequest = r
+			w.Write([]byte(`<html><head><title>Options</title></head><body><h1>Options</h1>
+				<a href="/about">About</a><a href="/gone">Gone</a>
+				<a href="` + other.URL + `/partner">Partner</a><a href="` + other.URL + `/other">Other</a></body></html>`))
+		case "/moved":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		case "/gone":
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	disabled := false
+
+	tests := []struct {
+		name    string
+		payload types.RequestPayload
+		status  int
+		check   func(t *testing.T, result types.AnalyzeResultes)
+	}{
+		{name: "Request headers", payload: types.RequestPayload{
+			UserAgent:      "team-bot/2.0",
+			AcceptLanguage: "de",
+			Headers:        map[string]string{"X-Team": "search"},
+		}, status: http.StatusOK, check: func(t *testing.T, result types.AnalyzeResultes) {
+			assert.Equal(t, "team-bot/2.0", pageRequest.UserAgent())
+			assert.Equal(t, "de", pageRequest.Header.Get("Accept-Language"))
+			assert.Equal(t, "search", pageRequest.Header.Get("X-Team"))
+			if assert.Len(t, linkRequests, 2) {
+				assert.Equal(t, "team-bot/2.0", linkRequests[0].UserAgent())
+				assert.Empty(t, linkRequests[0].Header.Get("X-Team"))
+			}
+		}},
+		{name: "Only selected checks", payload: types.RequestPayload{Checks: []string{"seo"}}, status: http.StatusOK, check: func(t *testing.T, result types.AnalyzeResultes) {
+			assert.Equal(t, "Options", result.Title)
+			assert.NotNil(t, result.SEO)
+			assert.Nil(t, result.Accessibility)
+			assert.Nil(t, result.HeadingOutline)
+			assert.Empty(t, result.Links)
+			assert.Empty(t, linkRequests)
+		}},
+		{name: "External links not checked", payload: types.RequestPayload{CheckExternalLinks: &disabled}, status: http.StatusOK, check: func(t *testing.T, result types.AnalyzeResultes) {
+			assert.Empty(t, linkRequests)
+			assert.Equal(t, 2, result.ExternalLinks)
+			assert.Equal(t, 0, result.BrokenExternalLinks)
+			assert.Equal(t, types.LinkStatusUnchecked, result.Links[2].Status)
+		}},
+		{name: "Internal links checked", payload: types.RequestPayload{CheckInternalLinks: true}, status: http.StatusOK, check: func(t *testing.T, result types.AnalyzeResultes) {
+			assert.Equal(t, types.LinkStatusAccessible, result.Links[0].Status)
+			assert.Equal(t, types.LinkStatusBroken, result.Links[1].Status)
+			assert.Equal(t, 2, result.InternalLinks)
+		}},
+		{name: "Max links", payload: types.RequestPayload{MaxLinks: 3}, status: http.StatusOK, check: func(t *testing.T, result types.AnalyzeResultes) {
+			assert.Len(t, result.Links, 3)
+			assert.True(t, result.Truncated)
+			assert.Len(t, linkRequests, 1)
+		}},
+		{name: "Redirect followed", payload: types.RequestPayload{URL: ts.URL + "/moved"}, status: http.StatusOK},
+		{name: "Redirect not followed", payload: types.RequestPayload{URL: ts.URL + "/moved", FollowRedirects: &disabled}, status: http.StatusInternalServerError},
+		{name: "Invalid option", payload: types.RequestPayload{Checks: []string{"spelling"}}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageRequest, linkRequests = nil, nil
+			if tt.payload.URL == "" {
+				tt.payload.URL = ts.URL
+			}
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			GetResults(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.check != nil {
+				var result types.AnalyzeResultes
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				tt.check(t, result)
+			}
+		})
+	}
+}
+
+func Test_fetchURL_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	opts := defaultOptions(ts.URL)
+	opts.timeout = 50 * time.Millisecond
+	_, err := fetchTimedURL(ts.URL, nil, opts)
+	assert.ErrorContains(t, err, "Timeout")
+}
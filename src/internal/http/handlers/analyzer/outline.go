@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// maxHeadingLength is the number of characters after which a heading is reported as too long
+const maxHeadingLength = 70
+
+// buildHeadingOutline returns the document's headings as a tree in document order
+func buildHeadingOutline(doc *goquery.Document) *types.HeadingOutline {
+	logrus.Debug("Building heading outline")
+	outline := &types.HeadingOutline{Headings: []*types.HeadingNode{}, Findings: []types.Finding{}}
+
+	var stack []*types.HeadingNode
+	h1Count := 0
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		node := &types.HeadingNode{
+			Level: headingLevel(s),
+			Text:  strings.Join(strings.Fields(s.Text()), " "),
+		}
+
+		if node.Level == 1 {
+			h1Count++
+			if h1Count == 2 {
+				addOutlineFinding(outline, s, types.SeverityWarning, "multiple-h1", "Page has more than one h1")
+			}
+		}
+		if node.Text == "" {
+			addOutlineFinding(outline, s, types.SeverityError, "empty-heading", "h%d heading is empty", node.Level)
+		} else if length := utf8.RuneCountInString(node.Text); length > maxHeadingLength {
+			addOutlineFinding(outline, s, types.SeverityInfo, "long-heading", "h%d heading is %d characters long", node.Level, length)
+		}
+
+		// Pop back to the closest heading of a higher level, which becomes the parent
+		for len(stack) > 0 && stack[len(stack)-1].Level >= node.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			outline.Headings = append(outline.Headings, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	})
+
+	if h1Count == 0 && len(outline.Headings) > 0 {
+		addOutlineFinding(outline, nil, types.SeverityWarning, "missing-h1", "Page has headings but no h1")
+	}
+
+	logrus.Debug("Heading outline built with ", len(outline.Findings), " findings")
+	return outline
+}
+
+// headingSkip is a heading more than one level below the heading before it
+type headingSkip struct {
+	selection *goquery.Selection
+	from, to  int
+}
+
+// findHeadingSkips returns the headings that skip levels, in document order
+func findHeadingSkips(doc *goquery.Document) []headingSkip {
+	var skips []headingSkip
+	previous := 0
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := headingLevel(s)
+		if previous > 0 && level > previous+1 {
+			skips = append(skips, headingSkip{selection: s, from: previous, to: level})
+		}
+		previous = level
+	})
+	return skips
+}
+
+// addSkippedLevelFindings reports skipped heading levels on the outline. The
+// accessibility audit reports them too, so it is only used when that is off.
+func addSkippedLevelFindings(doc *goquery.Document, outline *types.HeadingOutline) {
+	for _, skip := range findHeadingSkips(doc) {
+		addOutlineFinding(outline, skip.selection, types.SeverityWarning, "skipped-level", "Heading level skips from h%d to h%d", skip.from, skip.to)
+	}
+}
+
+func addOutlineFinding(outline *types.HeadingOutline, s *goquery.Selection, severity, check, format string, args ...interface{}) {
+	addFinding(&outline.Findings, severity, check, format, args...)
+	if s != nil {
+		outline.Findings[len(outline.Findings)-1].Selector = cssPath(s)
+	}
+}
+
+// headingLevel returns the numeric level of an h1-h6 element
+func headingLevel(s *goquery.Selection) int {
+	return int(goquery.NodeName(s)[1] - '0')
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_buildHeadingOutline(t *testing.T) {
+	html := `<html><body>
+		<h1>Guide</h1>
+		<h2>Install</h2>
+		<h3>Linux</h3>
+		<h3>macOS</h3>
+		<h2>Usage</h2>
+		<h1>Appendix</h1>
+	</body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	got := buildHeadingOutline(doc)
+
+	assert.Len(t, got.Headings, 2)
+	guide := got.Headings[0]
+	assert.Equal(t, "Guide", guide.Text)
+	assert.Len(t, guide.Children, 2)
+	assert.Equal(t, "Install", guide.Children[0].Text)
+	assert.Equal(t, []string{"Linux", "macOS"}, []string{guide.Children[0].Children[0].Text, guide.Children[0].Children[1].Text})
+	assert.Equal(t, "Usage", guide.Children[1].Text)
+	assert.Equal(t, "Appendix", got.Headings[1].Text)
+	assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "multiple-h1"))
+}
+
+func Test_buildHeadingOutline_Findings(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		severity string
+		check    string
+	}{
+		{name: "Skipped level", html: "<h1>A</h1><h4>B</h4>", severity: types.SeverityWarning, check: "skipped-level"},
+		{name: "Empty heading", html: "<h1>A</h1><h2>  </h2>", severity: types.SeverityError, check: "empty-heading"},
+		{name: "Long heading", html: "<h1>" + strings.Repeat("word ", 20) + "</h1>", severity: types.SeverityInfo, check: "long-heading"},
+		{name: "Missing h1", html: "<h2>A</h2>", severity: types.SeverityWarning, check: "missing-h1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			got := buildHeadingOutline(doc)
+			addSkippedLevelFindings(doc, got)
+			assert.True(t, hasFinding(got.Findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, got.Findings)
+		})
+	}
+}
+
+func Test_buildHeadingOutline_SkippedLevelOnce(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><body><h1>A</h1><h3>B</h3></body></html>"))
+
+	// The outline leaves skipped levels to the accessibility audit
+	assert.False(t, hasFinding(buildHeadingOutline(doc).Findings, types.SeverityWarning, "skipped-level"))
+	assert.True(t, hasFinding(auditAccessibility(doc).Findings, types.SeverityWarning, "heading-order"))
+}
@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+const (
+	// compressionMinSize is the decoded size below which compression is not worth flagging
+	compressionMinSize = 1024
+	// staticMinMaxAge is the shortest cache lifetime in seconds expected for static assets
+	staticMinMaxAge = 86400
+	// largestResources is how many of the heaviest responses are listed
+	largestResources = 5
+	// weightAcceptEncoding offers only the encodings limitedBody can decode, br is not among them
+	weightAcceptEncoding = "gzip, deflate"
+)
+
+// staticKinds are the resource kinds expected to be cacheable
+var staticKinds = toSet(types.ResourceScript, types.ResourceStylesheet, types.ResourceImage, types.ResourceFont, types.ResourceMedia)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// auditPageWeight fetches the page and its subresources and reports their sizes,
+// compression and caching
+func auditPageWeight(pageURL string, resources *types.ResourcesReport, opts *analysisOptions) *types.PageWeightReport {
+	logrus.Debug("Auditing page weight")
+	weights := []types.ResourceWeight{{URL: pageURL, Kind: types.ResourceDocument}}
+	if resources != nil {
+		for _, resource := range resources.Resources {
+			weights = append(weights, types.ResourceWeight{URL: resource.URL, Kind: resource.Kind})
+		}
+	}
+	// The document is always measured, the subresources share the link cap
+	skipped := 0
+	if opts.maxLinks > 0 && len(weights)-1 > opts.maxLinks {
+		skipped = len(weights) - 1 - opts.maxLinks
+		weights = weights[:opts.maxLinks+1]
+	}
+	forEachConcurrently(len(weights), func(i int) {
+		measureResource(&weights[i], opts)
+	})
+
+	report := &types.PageWeightReport{
+		AcceptEncoding: weightAcceptEncoding,
+		ByKind:         make(map[string]types.WeightTotals),
+		Largest:        []types.ResourceWeight{},
+		Resources:      weights,
+		Findings:       []types.Finding{},
+	}
+	if resources == nil {
+		report.Partial = true
+		addFinding(&report.Findings, types.SeverityInfo, "resources-skipped", "the resources check is disabled, only the document was measured")
+	}
+	if skipped > 0 {
+		report.Partial = true
+		addFinding(&report.Findings, types.SeverityInfo, "resources-capped", "only the first %d of %d subresources were measured", opts.maxLinks, opts.maxLinks+skipped)
+	}
+	for _, weight := range weights {
+		if weight.Error != "" {
+			addFinding(&report.Findings, types.SeverityWarning, "resource-unreachable", "%s could not be fetched: %s", weight.URL, weight.Error)
+			continue
+		}
+		report.Requests++
+		report.TotalTransferSize += weight.TransferSize
+		totals := report.ByKind[weight.Kind]
+		totals.Count++
+		totals.TransferSize += weight.TransferSize
+		if weight.DecodedSize > 0 {
+			report.TotalDecodedSize += weight.DecodedSize
+			totals.DecodedSize += weight.DecodedSize
+		}
+		report.ByKind[weight.Kind] = totals
+		report.Largest = append(report.Largest, weight)
+		if weight.Truncated != "" {
+			addFinding(&report.Findings, types.SeverityInfo, "resource-truncated", "%s was measured only in part: %s", weight.URL, weight.Truncated)
+		}
+
+		if weight.Status >= http.StatusBadRequest {
+			addFinding(&report.Findings, types.SeverityWarning, "resource-status", "%s returned status %d", weight.URL, weight.Status)
+			continue
+		}
+		checkCompression(weight, &report.Findings)
+		checkCaching(weight, &report.Findings)
+	}
+
+	sort.SliceStable(report.Largest, func(i, j int) bool {
+		return report.Largest[i].TransferSize > report.Largest[j].TransferSize
+	})
+	if len(report.Largest) > largestResources {
+		report.Largest = report.Largest[:largestResources]
+	}
+
+	logrus.Debug("Page weight is ", report.TotalTransferSize, " bytes over ", report.Requests, " requests")
+	return report
+}
+
+// measureResource downloads the resource without transparent decompression so
+// that both the transfer and the decoded size can be measured, reading at most
+// as much as the analyzer limits allow
+func measureResource(weight *types.ResourceWeight, opts *analysisOptions) {
+	weight.MaxAge = -1
+	req, err := opts.newRequest(http.MethodGet, weight.URL, nil)
+	if err != nil {
+		weight.Error = err.Error()
+		return
+	}
+	req.Header.Set("Accept-Encoding", weightAcceptEncoding)
+
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		weight.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	weight.Status = resp.StatusCode
+	weight.ContentType = resp.Header.Get("Content-Type")
+	weight.ContentEncoding = strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	weight.CacheControl = resp.Header.Get("Cache-Control")
+	weight.ETag = resp.Header.Get("ETag")
+	weight.MaxAge = freshnessLifetime(resp.Header)
+
+	weight.DecodedSize = -1
+	body, err := newLimitedBody(resp)
+	if err != nil {
+		// The encoding is broken, so only the transfer size can be measured
+		raw := &countingReader{reader: resp.Body}
+		if limits.MaxBodyBytes > 0 {
+			raw.reader = io.LimitReader(resp.Body, limits.MaxBodyBytes)
+		}
+		io.Copy(io.Discard, raw)
+		weight.TransferSize = raw.count
+		return
+	}
+	decoded, err := io.Copy(io.Discard, body)
+	weight.TransferSize = body.raw.count
+	weight.Truncated = body.truncated
+	switch weight.ContentEncoding {
+	case "", "identity", "gzip", "x-gzip", "deflate":
+		if err == nil {
+			weight.DecodedSize = decoded
+		}
+	}
+}
+
+// freshnessLifetime returns the max-age declared by Cache-Control, falling back
+// to Expires, or -1 when the response declares neither
+func freshnessLifetime(header http.Header) int64 {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if maxAge, err := strconv.ParseInt(strings.Trim(arg, `"`), 10, 64); err == nil {
+				return maxAge
+			}
+		}
+	}
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return -1
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return -1
+	}
+	if lifetime := int64(expires.Sub(date).Seconds()); lifetime > 0 {
+		return lifetime
+	}
+	return 0
+}
+
+func checkCompression(weight types.ResourceWeight, findings *[]types.Finding) {
+	if weight.ContentEncoding != "" && weight.ContentEncoding != "identity" {
+		return
+	}
+	if weight.DecodedSize < compressionMinSize || !isCompressible(weight.ContentType) {
+		return
+	}
+	addFinding(findings, types.SeverityWarning, "compression", "%s is served uncompressed (%d bytes), enable gzip or br", weight.URL, weight.DecodedSize)
+}
+
+// isCompressible reports whether the media type is text-based and benefits from compression
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json") ||
+		strings.Contains(mediaType, "javascript") ||
+		mediaType == "application/json" || mediaType == "application/xml" ||
+		mediaType == "application/wasm" || mediaType == "image/svg+xml"
+}
+
+func checkCaching(weight types.ResourceWeight, findings *[]types.Finding) {
+	cacheControl := strings.ToLower(weight.CacheControl)
+	switch {
+	case !staticKinds[weight.Kind]:
+		return
+	case strings.Contains(cacheControl, "no-store"):
+		addFinding(findings, types.SeverityInfo, "cache-no-store", "%s is marked no-store and is downloaded on every visit", weight.URL)
+	case weight.MaxAge < 0 && weight.ETag == "":
+		addFinding(findings, types.SeverityWarning, "cache-missing", "%s has no Cache-Control max-age, Expires or ETag", weight.URL)
+	case weight.MaxAge < staticMinMaxAge && weight.ETag == "":
+		addFinding(findings, types.SeverityWarning, "cache-short", "%s is cached for %d seconds without an ETag to revalidate", weight.URL, max(weight.MaxAge, 0))
+	case weight.MaxAge < staticMinMaxAge:
+		addFinding(findings, types.SeverityInfo, "cache-short", "%s is cached for %d seconds, at least %d is recommended for static assets", weight.URL, max(weight.MaxAge, 0), staticMinMaxAge)
+	}
+}
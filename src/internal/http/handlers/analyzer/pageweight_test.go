@@ -0,0 +1,204 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func newPageWeightSite() *httptest.Server {
+	script := strings.Repeat("console.log('page weight');\n", 200)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(script))
+	gz.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><script src="/app.js"></script><link rel="stylesheet" href="/site.css"></head>
+			<body><img src="/logo.png"><img src="/missing.png"></body></html>`))
+	})
+	mux.HandleFunc("/app.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Write(gzipped.Bytes())
+	})
+	mux.HandleFunc("/site.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(strings.Repeat("body { margin: 0; }\n", 300)))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(make([]byte, 20000))
+	})
+	mux.HandleFunc("/missing.png", http.NotFound)
+	return httptest.NewServer(mux)
+}
+
+func Test_auditPageWeight(t *testing.T) {
+	ts := newPageWeightSite()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	assert.NoError(t, err)
+	doc, _ := goquery.NewDocumentFromReader(resp.Body)
+	resp.Body.Close()
+	pageURL, _ := url.Parse(ts.URL)
+
+	got := auditPageWeight(ts.URL, inventoryResources(doc, pageURL), defaultOptions(ts.URL))
+
+	assert.Equal(t, "gzip, deflate", got.AcceptEncoding)
+	assert.False(t, got.Partial)
+	assert.Equal(t, 5, got.Requests)
+	if assert.Len(t, got.Resources, 5) {
+		document, script, css := got.Resources[0], got.Resources[1], got.Resources[2]
+		assert.Equal(t, types.ResourceDocument, document.Kind)
+		assert.Equal(t, "gzip", script.ContentEncoding)
+		assert.Less(t, script.TransferSize, script.DecodedSize)
+		assert.Equal(t, int64(len(strings.Repeat("console.log('page weight');\n", 200))), script.DecodedSize)
+		assert.Equal(t, int64(31536000), script.MaxAge)
+		assert.Equal(t, css.TransferSize, css.DecodedSize)
+		assert.Equal(t, int64(-1), css.MaxAge)
+	}
+
+	assert.Equal(t, types.WeightTotals{Count: 2, TransferSize: 20000 + 19, DecodedSize: 20000 + 19}, got.ByKind[types.ResourceImage])
+	assert.Equal(t, ts.URL+"/logo.png", got.Largest[0].URL)
+	assert.Greater(t, got.TotalDecodedSize, got.TotalTransferSize)
+
+	assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "resource-status"))
+	assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "compression"))
+	assert.True(t, hasFinding(got.Findings, types.SeverityWarning, "cache-missing"))
+	assert.True(t, hasFinding(got.Findings, types.SeverityInfo, "cache-short"))
+	for _, finding := range got.Findings {
+		assert.NotContains(t, finding.Message, "/app.js", "the gzipped, long-cached script should not be flagged")
+	}
+}
+
+func Test_measureResource(t *testing.T) {
+	text := []byte(strings.Repeat("body { margin: 0; }\n", 300))
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write(text)
+	zw.Close()
+	bomb := gzipped(t, make([]byte, 4<<20))
+
+	tests := []struct {
+		name          string
+		encoding      string
+		body          []byte
+		wantDecoded   int64
+		wantTruncated bool
+	}{
+		{name: "Deflate is zlib-wrapped", encoding: "deflate", body: deflated.Bytes(), wantDecoded: int64(len(text))},
+		{name: "Unsupported encoding", encoding: "br", body: []byte("not really brotli"), wantDecoded: -1},
+		{name: "Decompression bomb", encoding: "gzip", body: bomb, wantTruncated: true},
+	}
+
+	withLimits(t, config.Analyzer{MaxBodyBytes: 10 << 20, MaxDecompressionRatio: 100})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acceptEncoding string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.Write(tt.body)
+			}))
+			defer ts.Close()
+
+			weight := types.ResourceWeight{URL: ts.URL}
+			measureResource(&weight, defaultOptions(ts.URL))
+
+			assert.Equal(t, "gzip, deflate", acceptEncoding)
+			assert.Empty(t, weight.Error)
+			assert.Equal(t, tt.wantTruncated, weight.Truncated != "")
+			if tt.wantTruncated {
+				assert.Less(t, weight.DecodedSize, int64(4<<20))
+				assert.Less(t, weight.TransferSize, int64(len(tt.body)))
+			} else {
+				assert.Equal(t, tt.wantDecoded, weight.DecodedSize)
+				assert.Equal(t, int64(len(tt.body)), weight.TransferSize)
+			}
+		})
+	}
+}
+
+func Test_auditPageWeight_ResourceCap(t *testing.T) {
+	ts := newPageWeightSite()
+	defer ts.Close()
+	resources := &types.ResourcesReport{}
+	for i := 0; i < 5; i++ {
+		resources.Resources = append(resources.Resources, types.Resource{URL: ts.URL + "/logo.png", Kind: types.ResourceImage})
+	}
+	opts := defaultOptions(ts.URL)
+	opts.maxLinks = 2
+
+	got := auditPageWeight(ts.URL, resources, opts)
+
+	assert.Len(t, got.Resources, 3)
+	assert.True(t, got.Partial)
+	assert.True(t, hasFinding(got.Findings, types.SeverityInfo, "resources-capped"))
+}
+
+func Test_auditPageWeight_ResourcesSkipped(t *testing.T) {
+	ts := newPageWeightSite()
+	defer ts.Close()
+
+	got := auditPageWeight(ts.URL, nil, defaultOptions(ts.URL))
+
+	assert.Equal(t, 1, got.Requests)
+	assert.True(t, got.Partial)
+	assert.True(t, hasFinding(got.Findings, types.SeverityInfo, "resources-skipped"))
+}
+
+func Test_freshnessLifetime(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int64
+	}{
+		{name: "Max-age", header: http.Header{"Cache-Control": {"public, max-age=3600"}}, want: 3600},
+		{name: "Expires", header: http.Header{"Date": {"Mon, 02 Jan 2006 15:04:05 GMT"}, "Expires": {"Mon, 02 Jan 2006 16:04:05 GMT"}}, want: 3600},
+		{name: "Max-age overrides Expires", header: http.Header{"Cache-Control": {"max-age=60"}, "Date": {"Mon, 02 Jan 2006 15:04:05 GMT"}, "Expires": {"Mon, 02 Jan 2006 16:04:05 GMT"}}, want: 60},
+		{name: "Expired", header: http.Header{"Date": {"Mon, 02 Jan 2006 15:04:05 GMT"}, "Expires": {"0"}}, want: -1},
+		{name: "None", header: http.Header{}, want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, freshnessLifetime(tt.header))
+		})
+	}
+}
+
+func Test_GetResults_PageWeightOptIn(t *testing.T) {
+	ts := newPageWeightSite()
+	defer ts.Close()
+
+	for _, enabled := range []bool{false, true} {
+		body, _ := json.Marshal(types.RequestPayload{URL: ts.URL, PageWeight: enabled})
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		GetResults(rec, req)
+
+		var result types.AnalyzeResultes
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, enabled, result.PageWeight != nil)
+	}
+}
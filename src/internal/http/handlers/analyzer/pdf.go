@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// maxPDFStreamSize caps how much a single compressed stream may inflate to
+const maxPDFStreamSize = 10 << 20
+
+// pdfStringPattern matches a literal (...) or hex <...> PDF string
+const pdfStringPattern = `(\((?:\\.|[^\\)])*\)|<[0-9A-Fa-f\s]*>)`
+
+var (
+	pdfVersionRegex = regexp.MustCompile(`^%PDF-(\d\.\d)`)
+	pdfStreamRegex  = regexp.MustCompile(`>>\s*stream\r?\n`)
+	pdfPageRegex    = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfURIRegex     = regexp.MustCompile(`/URI\s*` + pdfStringPattern)
+	pdfInfoRegex    = regexp.MustCompile(`/(Title|Author|Subject|Creator|Producer)\s*` + pdfStringPattern)
+)
+
+// analyzePDF reads the document information dictionary, page count and link
+// annotations of a PDF. Compressed streams are inflated so that objects stored
+// in object streams are found too.
+func analyzePDF(body []byte, resp *http.Response, pageURL *url.URL, opts *analysisOptions) (*types.AnalyzeResultes, error) {
+	logrus.Info("Extracting data from PDF")
+	content, inflateBudget := inflatePDFStreams(body)
+
+	info := &types.DocumentInfo{Format: "PDF", Pages: len(pdfPageRegex.FindAll(content, -1))}
+	if match := pdfVersionRegex.FindSubmatch(body); match != nil {
+		info.Version = string(match[1])
+	}
+
+	result := &types.AnalyzeResultes{Headings: make(map[string]int), Document: info}
+	if inflateBudget > 0 {
+		markTruncated(result, "the PDF streams inflate beyond %d bytes, the rest was not read", inflateBudget)
+	}
+	// Incremental updates append newer dictionaries, so later entries win
+	for _, match := range pdfInfoRegex.FindAllSubmatch(content, -1) {
+		value := decodePDFString(match[2])
+		switch string(match[1]) {
+		case "Title":
+			result.Title = value
+		case "Author":
+			info.Author = value
+		case "Subject":
+			info.Subject = value
+		case "Creator":
+			info.Creator = value
+		case "Producer":
+			info.Producer = value
+		}
+	}
+
+	if opts.enabled("links") {
+		var uris []string
+		for _, match := range pdfURIRegex.FindAllSubmatch(content, -1) {
+			uris = append(uris, decodePDFString(match[1]))
+		}
+		checkLinks(result, linksFromURLs(uris, pageURL), opts)
+	}
+	return result, nil
+}
+
+// inflatePDFStreams returns the body followed by the inflated contents of every
+// stream that decompresses with zlib. All streams share one budget, the body
+// size times the allowed decompression ratio, so many small bombs cannot add
+// up; when it runs out the budget is returned, otherwise zero.
+func inflatePDFStreams(body []byte) ([]byte, int64) {
+	budget := pdfInflateBudget(len(body))
+	remaining := budget
+	content := append([]byte{}, body...)
+	for _, loc := range pdfStreamRegex.FindAllIndex(body, -1) {
+		if budget > 0 && remaining <= 0 {
+			logrus.Warn("Stopped inflating PDF streams after ", budget, " bytes")
+			return content, budget
+		}
+		data := body[loc[1]:]
+		if end := bytes.Index(data, []byte("endstream")); end >= 0 {
+			data = data[:end]
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		limit := int64(maxPDFStreamSize)
+		if budget > 0 && remaining < limit {
+			limit = remaining
+		}
+		inflated, err := io.ReadAll(io.LimitReader(reader, limit))
+		reader.Close()
+		if err != nil && len(inflated) == 0 {
+			continue
+		}
+		remaining -= int64(len(inflated))
+		content = append(append(content, '\n'), inflated...)
+	}
+	return content, 0
+}
+
+// pdfInflateBudget is how much the streams of a PDF of size bytes may inflate
+// to in total: the decompression ratio allows, capped at the body size limit as
+// the inflated streams are held in memory. It is zero when neither is limited.
+func pdfInflateBudget(size int) int64 {
+	var budget int64
+	if limits.MaxDecompressionRatio > 0 {
+		budget = max(int64(float64(size)*limits.MaxDecompressionRatio), ratioCheckMinBytes)
+	}
+	if limits.MaxBodyBytes > 0 && (budget == 0 || limits.MaxBodyBytes < budget) {
+		budget = limits.MaxBodyBytes
+	}
+	return budget
+}
+
+// decodePDFString decodes a literal or hex string token, handling escapes and
+// UTF-16BE strings marked with a byte order mark
+func decodePDFString(token []byte) string {
+	var raw []byte
+	if token[0] == '<' {
+		raw, _ = hex.DecodeString(strings.Join(strings.Fields(string(token[1:len(token)-1])), ""))
+	} else {
+		raw = unescapePDFLiteral(token[1 : len(token)-1])
+	}
+
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	// PDFDocEncoding matches Latin-1 for printable characters
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func unescapePDFLiteral(literal []byte) []byte {
+	escapes := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'b': '\b', 'f': '\f'}
+	var out []byte
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' || i+1 == len(literal) {
+			out = append(out, literal[i])
+			continue
+		}
+		i++
+		switch c := literal[i]; {
+		case escapes[c] != 0:
+			out = append(out, escapes[c])
+		case c >= '0' && c <= '7':
+			value := 0
+			for n := 0; n < 3 && i < len(literal) && literal[i] >= '0' && literal[i] <= '7'; n++ {
+				value = value*8 + int(literal[i]-'0')
+				i++
+			}
+			i--
+			out = append(out, byte(value))
+		case c == '\r' || c == '\n':
+			// A backslash before a line break continues the string
+			if c == '\r' && i+1 < len(literal) && literal[i+1] == '\n' {
+				i++
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_decodePDFString(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "Literal", token: `(Annual report)`, want: "Annual report"},
+		{name: "Escapes", token: `(Q\(3\) \\ results\n)`, want: "Q(3) \\ results\n"},
+		{name: "Octal", token: `(caf\351)`, want: "café"},
+		{name: "Hex", token: `<48656C6C6F>`, want: "Hello"},
+		{name: "UTF-16 hex", token: `<FEFF 65E5 672C>`, want: "日本"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodePDFString([]byte(tt.token)))
+		})
+	}
+}
+
+func Test_analyzePage_PDF(t *testing.T) {
+	var annotations bytes.Buffer
+	zw := zlib.NewWriter(&annotations)
+	fmt.Fprint(zw, "5 0 obj << /Type /Annot /Subtype /Link /A << /S /URI /URI (http://127.0.0.1:1/spec) >> >> endobj")
+	// Padding makes the stream compressible so the URI is only visible once inflated
+	fmt.Fprint(zw, strings.Repeat(" ", 512))
+	zw.Close()
+
+	var pdf bytes.Buffer
+	fmt.Fprint(&pdf, "%PDF-1.7\n")
+	fmt.Fprint(&pdf, "1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	fmt.Fprint(&pdf, "2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n")
+	fmt.Fprint(&pdf, "3 0 obj << /Type /Page /Parent 2 0 R >> endobj\n")
+	fmt.Fprint(&pdf, "4 0 obj << /Type /Page /Parent 2 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "6 0 obj << /Type /ObjStm /Filter /FlateDecode /Length %d >>\nstream\n", annotations.Len())
+	pdf.Write(annotations.Bytes())
+	fmt.Fprint(&pdf, "\nendstream\nendobj\n")
+	fmt.Fprint(&pdf, "7 0 obj << /Title (Quarterly results) /Author <FEFF004A006F> /Producer (Writer) >> endobj\n")
+	pdf.WriteString("trailer << /Root 1 0 R /Info 7 0 R >>\n%%EOF\n")
+
+	assert.NotContains(t, pdf.String(), "/URI", "the annotation must only be reachable through the compressed stream")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf.Bytes())
+	}))
+	defer ts.Close()
+
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "application/pdf", result.ContentType)
+	assert.Equal(t, "Quarterly results", result.Title)
+	assert.Equal(t, types.DocumentInfo{Format: "PDF", Version: "1.7", Author: "Jo", Producer: "Writer", Pages: 2}, *result.Document)
+	if assert.Len(t, result.Links, 1) {
+		assert.Equal(t, "http://127.0.0.1:1/spec", result.Links[0].Href)
+		assert.Equal(t, types.LinkTypeExternal, result.Links[0].Type)
+	}
+}
+
+func Test_inflatePDFStreams_SharedBudget(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := zlib.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	// Each stream stays far below the per-stream cap, together they inflate to 200MB
+	var pdf bytes.Buffer
+	fmt.Fprint(&pdf, "%PDF-1.7\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&pdf, "%d 0 obj << /Filter /FlateDecode /Length %d >>\nstream\n", i+1, bomb.Len())
+		pdf.Write(bomb.Bytes())
+		fmt.Fprint(&pdf, "\nendstream\nendobj\n")
+	}
+	withLimits(t, config.Analyzer{MaxBodyBytes: 10 << 20, MaxDecompressionRatio: 100})
+
+	content, budget := inflatePDFStreams(pdf.Bytes())
+
+	assert.Equal(t, int64(10<<20), budget)
+	assert.LessOrEqual(t, int64(len(content)), int64(pdf.Len())+budget+200)
+}
+
+func Test_inflatePDFStreams_BodyLimit(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := zlib.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	// A PDF just under the body limit, padded so the ratio alone would allow 100MB
+	var pdf bytes.Buffer
+	fmt.Fprint(&pdf, "%PDF-1.7\n")
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(&pdf, "%d 0 obj << /Filter /FlateDecode /Length %d >>\nstream\n", i+1, bomb.Len())
+		pdf.Write(bomb.Bytes())
+		fmt.Fprint(&pdf, "\nendstream\nendobj\n")
+	}
+	pdf.WriteString("%" + strings.Repeat("x", 1<<20-pdf.Len()-100) + "\n")
+	withLimits(t, config.Analyzer{MaxBodyBytes: 1 << 20, MaxDecompressionRatio: 100})
+
+	content, budget := inflatePDFStreams(pdf.Bytes())
+
+	assert.Equal(t, int64(1<<20), budget)
+	assert.LessOrEqual(t, int64(len(content)), int64(pdf.Len())+budget+4)
+}
@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+var fontExtensions = toSet(".woff2", ".woff", ".ttf", ".otf", ".eot")
+
+// inventoryResources lists the subresources the page references and groups them
+// by registrable domain, so that subdomains of the site count as first party
+func inventoryResources(doc *goquery.Document, pageURL *url.URL) *types.ResourcesReport {
+	logrus.Debug("Inventorying subresources")
+	report := &types.ResourcesReport{Resources: []types.Resource{}, Domains: []types.DomainSummary{}}
+	siteDomain := registrableDomain(pageURL.Hostname())
+
+	seen := make(map[string]bool)
+	domains := make(map[string]*types.DomainSummary)
+	for _, ref := range collectResourceRefs(doc, pageURL) {
+		kind := resourceKind(ref)
+		if kind == "" || (ref.url.Scheme != "http" && ref.url.Scheme != "https") {
+			continue
+		}
+		key, err := normalizeURL(ref.url.String())
+		if err != nil {
+			key = ref.url.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		resource := types.Resource{
+			URL:     ref.url.String(),
+			Kind:    kind,
+			Element: ref.element,
+			Domain:  registrableDomain(ref.url.Hostname()),
+		}
+		resource.ThirdParty = resource.Domain != siteDomain
+		report.Resources = append(report.Resources, resource)
+
+		if resource.ThirdParty {
+			report.ThirdParty++
+		} else {
+			report.FirstParty++
+		}
+		summary, ok := domains[resource.Domain]
+		if !ok {
+			summary = &types.DomainSummary{Domain: resource.Domain, ThirdParty: resource.ThirdParty, Kinds: make(map[string]int)}
+			domains[resource.Domain] = summary
+		}
+		summary.Count++
+		summary.Kinds[kind]++
+	}
+
+	for _, summary := range domains {
+		report.Domains = append(report.Domains, *summary)
+	}
+	sort.Slice(report.Domains, func(i, j int) bool {
+		if report.Domains[i].Count != report.Domains[j].Count {
+			return report.Domains[i].Count > report.Domains[j].Count
+		}
+		return report.Domains[i].Domain < report.Domains[j].Domain
+	})
+
+	logrus.Debug("Found ", len(report.Resources), " subresources across ", len(report.Domains), " domains")
+	return report
+}
+
+// preloadKinds maps the as attribute of preload hints to the kind they load
+var preloadKinds = map[string]string{
+	"font":   types.ResourceFont,
+	"style":  types.ResourceStylesheet,
+	"script": types.ResourceScript,
+	"worker": types.ResourceScript,
+	"image":  types.ResourceImage,
+	"audio":  types.ResourceMedia,
+	"video":  types.ResourceMedia,
+	"track":  types.ResourceMedia,
+	"fetch":  types.ResourceOther,
+}
+
+// resourceKind reports what a reference loads, or "" for references that are
+// not subresources such as form actions
+func resourceKind(ref resourceRef) string {
+	switch ref.element {
+	case "script":
+		return types.ResourceScript
+	case "link":
+		rels := toSet(strings.Fields(strings.ToLower(ref.selection.AttrOr("rel", "")))...)
+		switch {
+		case rels["stylesheet"]:
+			return types.ResourceStylesheet
+		case rels["preload"], rels["modulepreload"], rels["prefetch"]:
+			// A hint loads whatever its as attribute names, modulepreload defaults to a script
+			as := strings.ToLower(strings.TrimSpace(ref.selection.AttrOr("as", "")))
+			if as == "" && rels["modulepreload"] {
+				as = "script"
+			}
+			if kind, ok := preloadKinds[as]; ok {
+				return kind
+			}
+			return types.ResourcePreload
+		case rels["manifest"]:
+			return types.ResourceOther
+		}
+		return types.ResourceImage
+	case "img", "input":
+		return types.ResourceImage
+	case "iframe", "frame", "embed", "object":
+		return types.ResourceFrame
+	case "audio", "track":
+		return types.ResourceMedia
+	case "video":
+		if ref.attribute == "poster" {
+			return types.ResourceImage
+		}
+		return types.ResourceMedia
+	case "source":
+		if goquery.NodeName(ref.selection.Parent()) == "picture" {
+			return types.ResourceImage
+		}
+		return types.ResourceMedia
+	case "form":
+		return ""
+	}
+
+	// CSS references from <style> elements and style attributes
+	if ref.cssImport {
+		return types.ResourceStylesheet
+	}
+	if fontExtensions[strings.ToLower(path.Ext(ref.url.Path))] {
+		return types.ResourceFont
+	}
+	return types.ResourceImage
+}
@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_resourceKind(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "Script", html: `<script src="/app.js"></script>`, want: types.ResourceScript},
+		{name: "Stylesheet", html: `<link rel="stylesheet" href="/site.css">`, want: types.ResourceStylesheet},
+		{name: "Preloaded font", html: `<link rel="preload" as="font" href="/font.woff2" crossorigin>`, want: types.ResourceFont},
+		{name: "Preloaded style", html: `<link rel="preload" as="style" href="/site.css">`, want: types.ResourceStylesheet},
+		{name: "Preloaded image", html: `<link rel="preload" as="IMAGE" href="/hero.jpg">`, want: types.ResourceImage},
+		{name: "Prefetched data", html: `<link rel="prefetch" as="fetch" href="/data.json">`, want: types.ResourceOther},
+		{name: "Module preload", html: `<link rel="modulepreload" href="/app.mjs">`, want: types.ResourceScript},
+		{name: "Preload without as", html: `<link rel="preload" href="/unknown">`, want: types.ResourcePreload},
+		{name: "Preload with unknown as", html: `<link rel="prefetch" as="document" href="/next">`, want: types.ResourcePreload},
+		{name: "Icon", html: `<link rel="shortcut icon" href="/favicon.ico">`, want: types.ResourceImage},
+		{name: "Image", html: `<img src="/a.png">`, want: types.ResourceImage},
+		{name: "Picture source", html: `<picture><source srcset="/a.webp"></picture>`, want: types.ResourceImage},
+		{name: "Video source", html: `<video><source src="/a.mp4"></video>`, want: types.ResourceMedia},
+		{name: "Video poster", html: `<video poster="/poster.jpg"></video>`, want: types.ResourceImage},
+		{name: "Iframe", html: `<iframe src="/embed"></iframe>`, want: types.ResourceFrame},
+		{name: "Font in style", html: `<style>@font-face { src: url(/fonts/a.woff2) }</style>`, want: types.ResourceFont},
+		{name: "Style import", html: `<style>@import url("/extra.css");</style>`, want: types.ResourceStylesheet},
+		{name: "Form action", html: `<form action="/submit"></form>`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			pageURL, _ := url.Parse("https://example.com/")
+			refs := collectResourceRefs(doc, pageURL)
+			if assert.Len(t, refs, 1) {
+				assert.Equal(t, tt.want, resourceKind(refs[0]))
+			}
+		})
+	}
+}
+
+func Test_inventoryResources(t *testing.T) {
+	page := `<html><head>
+		<link rel="stylesheet" href="https://static.example.co.uk/site.css">
+		<link rel="canonical" href="https://www.example.co.uk/">
+		<script src="https://www.googletagmanager.com/gtag/js"></script>
+		<script src="https://cdn.jsdelivr.net/npm/a.js"></script>
+		<script src="https://cdn.jsdelivr.net/npm/b.js"></script>
+	</head><body>
+		<img src="/logo.png"><img src="/logo.png">
+		<img src="data:image/png;base64,AAAA">
+		<iframe src="https://www.youtube.com/embed/x"></iframe>
+	</body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(page))
+	pageURL, _ := url.Parse("https://www.example.co.uk/")
+
+	got := inventoryResources(doc, pageURL)
+
+	assert.Len(t, got.Resources, 6)
+	assert.Equal(t, 2, got.FirstParty)
+	assert.Equal(t, 4, got.ThirdParty)
+	assert.Equal(t, "https://www.example.co.uk/logo.png", got.Resources[4].URL)
+	assert.False(t, got.Resources[0].ThirdParty, "subdomains of the site are first party")
+
+	if assert.Len(t, got.Domains, 4) {
+		assert.Equal(t, types.DomainSummary{Domain: "example.co.uk", Count: 2, Kinds: map[string]int{types.ResourceStylesheet: 1, types.ResourceImage: 1}}, got.Domains[0])
+		assert.Equal(t, types.DomainSummary{Domain: "jsdelivr.net", ThirdParty: true, Count: 2, Kinds: map[string]int{types.ResourceScript: 2}}, got.Domains[1])
+		assert.Equal(t, "googletagmanager.com", got.Domains[2].Domain)
+		assert.Equal(t, "youtube.com", got.Domains[3].Domain)
+	}
+}
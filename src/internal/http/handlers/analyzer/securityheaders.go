@@ -0,0 +1,217 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// HSTS max-age thresholds in seconds
+const (
+	hstsMinMaxAge     = 15552000 // 180 days
+	hstsPreloadMaxAge = 31536000 // 1 year
+)
+
+// auditedHeaders are the response headers copied into the report
+var auditedHeaders = []string{
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"Content-Security-Policy-Report-Only",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+	"Cross-Origin-Opener-Policy",
+	"Cross-Origin-Embedder-Policy",
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-AspNetMvc-Version",
+}
+
+var versionRegex = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// securityHeaderAudit accumulates findings and score deductions
+type securityHeaderAudit struct {
+	report *types.SecurityHeadersReport
+}
+
+func (a *securityHeaderAudit) add(penalty int, severity, check, format string, args ...interface{}) {
+	a.report.Score -= penalty
+	addFinding(&a.report.Findings, severity, check, format, args...)
+}
+
+// auditSecurityHeaders grades the security-related response headers of the page
+func auditSecurityHeaders(header http.Header, pageURL *url.URL) *types.SecurityHeadersReport {
+	logrus.Debug("Auditing security headers")
+	report := &types.SecurityHeadersReport{
+		Headers:  make(map[string]string),
+		Score:    100,
+		Findings: []types.Finding{},
+	}
+	for _, name := range auditedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			report.Headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	audit := &securityHeaderAudit{report: report}
+	audit.checkTransportSecurity(header, pageURL)
+	audit.checkContentSecurityPolicy(header)
+	audit.checkFraming(header)
+	audit.checkContentTypeOptions(header)
+	audit.checkReferrerPolicy(header)
+	audit.checkPermissionsPolicy(header)
+	audit.checkCrossOriginIsolation(header)
+	audit.checkInformationLeaks(header)
+
+	if report.Score < 0 {
+		report.Score = 0
+	}
+	report.Grade = securityGrade(report.Score)
+	logrus.Debug("Security headers graded ", report.Grade, " (", report.Score, ")")
+	return report
+}
+
+func (a *securityHeaderAudit) checkTransportSecurity(header http.Header, pageURL *url.URL) {
+	if pageURL.Scheme != "https" {
+		a.add(30, types.SeverityError, "https", "Page is served over plain HTTP")
+		return
+	}
+
+	value := header.Get("Strict-Transport-Security")
+	if value == "" {
+		a.add(20, types.SeverityError, "hsts", "Strict-Transport-Security header is missing")
+		return
+	}
+
+	hsts := parseHSTS(value)
+	a.report.HSTS = hsts
+	switch {
+	case hsts.MaxAge == 0:
+		a.add(20, types.SeverityError, "hsts", "HSTS max-age is 0, which disables HSTS")
+	case hsts.MaxAge < hstsMinMaxAge:
+		a.add(10, types.SeverityWarning, "hsts", "HSTS max-age is %d seconds, at least %d is recommended", hsts.MaxAge, hstsMinMaxAge)
+	}
+	if !hsts.IncludeSubDomains {
+		a.add(0, types.SeverityInfo, "hsts", "HSTS does not include subdomains")
+	}
+	if hsts.Preload && !hsts.PreloadEligible {
+		a.add(5, types.SeverityWarning, "hsts", "HSTS requests preload but needs max-age >= %d and includeSubDomains to be eligible", hstsPreloadMaxAge)
+	}
+}
+
+// parseHSTS reads the directives of a Strict-Transport-Security header
+func parseHSTS(value string) *types.HSTSInfo {
+	hsts := &types.HSTSInfo{}
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			hsts.MaxAge, _ = strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64)
+		case "includesubdomains":
+			hsts.IncludeSubDomains = true
+		case "preload":
+			hsts.Preload = true
+		}
+	}
+	hsts.PreloadEligible = hsts.MaxAge >= hstsPreloadMaxAge && hsts.IncludeSubDomains && hsts.Preload
+	return hsts
+}
+
+func (a *securityHeaderAudit) checkContentSecurityPolicy(header http.Header) {
+	if header.Get("Content-Security-Policy") != "" {
+		return
+	}
+	if header.Get("Content-Security-Policy-Report-Only") != "" {
+		a.add(10, types.SeverityWarning, "csp", "Content-Security-Policy is only set in report-only mode")
+		return
+	}
+	a.add(20, types.SeverityError, "csp", "Content-Security-Policy header is missing")
+}
+
+func (a *securityHeaderAudit) checkFraming(header http.Header) {
+	for _, policy := range collectCSP(nil, header) {
+		if _, ok := policy.Directives["frame-ancestors"]; ok && !policy.ReportOnly {
+			return
+		}
+	}
+
+	value := strings.ToUpper(strings.TrimSpace(header.Get("X-Frame-Options")))
+	switch {
+	case value == "DENY" || value == "SAMEORIGIN":
+	case value == "":
+		a.add(15, types.SeverityError, "framing", "Neither X-Frame-Options nor CSP frame-ancestors protects against clickjacking")
+	default:
+		a.add(10, types.SeverityWarning, "framing", "X-Frame-Options value %q is not supported by modern browsers", value)
+	}
+}
+
+func (a *securityHeaderAudit) checkContentTypeOptions(header http.Header) {
+	value := header.Get("X-Content-Type-Options")
+	if !strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		a.add(10, types.SeverityWarning, "content-type-options", "X-Content-Type-Options should be set to nosniff")
+	}
+}
+
+func (a *securityHeaderAudit) checkReferrerPolicy(header http.Header) {
+	value := header.Get("Referrer-Policy")
+	if value == "" {
+		a.add(5, types.SeverityWarning, "referrer-policy", "Referrer-Policy header is missing")
+		return
+	}
+	// The last recognised token wins, so only it matters
+	tokens := strings.Split(value, ",")
+	policy := strings.ToLower(strings.TrimSpace(tokens[len(tokens)-1]))
+	if policy == "unsafe-url" || policy == "no-referrer-when-downgrade" {
+		a.add(5, types.SeverityWarning, "referrer-policy", "Referrer-Policy %q leaks full URLs to other origins", policy)
+	}
+}
+
+func (a *securityHeaderAudit) checkPermissionsPolicy(header http.Header) {
+	if header.Get("Permissions-Policy") == "" {
+		a.add(5, types.SeverityInfo, "permissions-policy", "Permissions-Policy header is missing")
+	}
+}
+
+func (a *securityHeaderAudit) checkCrossOriginIsolation(header http.Header) {
+	if header.Get("Cross-Origin-Opener-Policy") == "" {
+		a.add(5, types.SeverityInfo, "coop", "Cross-Origin-Opener-Policy header is missing")
+	}
+	if header.Get("Cross-Origin-Embedder-Policy") == "" {
+		a.add(0, types.SeverityInfo, "coep", "Cross-Origin-Embedder-Policy header is missing")
+	}
+}
+
+func (a *securityHeaderAudit) checkInformationLeaks(header http.Header) {
+	if server := header.Get("Server"); versionRegex.MatchString(server) {
+		a.add(5, types.SeverityWarning, "information-leak", "Server header discloses the software version: %s", server)
+	}
+	for _, name := range []string{"X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version"} {
+		if value := header.Get(name); value != "" {
+			a.add(5, types.SeverityWarning, "information-leak", "%s header discloses the technology stack: %s", name, value)
+		}
+	}
+}
+
+// securityGrade converts a score out of 100 into a letter grade
+func securityGrade(score int) string {
+	switch {
+	case score == 100:
+		return "A+"
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
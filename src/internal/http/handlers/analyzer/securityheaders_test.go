@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_auditSecurityHeaders(t *testing.T) {
+	secure := http.Header{
+		"Strict-Transport-Security":    {"max-age=63072000; includeSubDomains; preload"},
+		"Content-Security-Policy":      {"default-src 'self'; frame-ancestors 'none'"},
+		"X-Content-Type-Options":       {"nosniff"},
+		"Referrer-Policy":              {"strict-origin-when-cross-origin"},
+		"Permissions-Policy":           {"camera=()"},
+		"Cross-Origin-Opener-Policy":   {"same-origin"},
+		"Cross-Origin-Embedder-Policy": {"require-corp"},
+	}
+
+	tests := []struct {
+		name     string
+		page     string
+		modify   func(http.Header)
+		score    int
+		grade    string
+		severity string
+		check    string
+	}{
+		{name: "All headers set", page: "https://example.com", modify: func(h http.Header) {}, score: 100, grade: "A+"},
+		{name: "Plain HTTP", page: "http://example.com", modify: func(h http.Header) {}, score: 70, grade: "C", severity: types.SeverityError, check: "https"},
+		{name: "Missing HSTS", page: "https://example.com", modify: func(h http.Header) { h.Del("Strict-Transport-Security") }, score: 80, grade: "B", severity: types.SeverityError, check: "hsts"},
+		{name: "Short HSTS", page: "https://example.com", modify: func(h http.Header) { h.Set("Strict-Transport-Security", "max-age=3600; includeSubDomains") }, score: 90, grade: "A", severity: types.SeverityWarning, check: "hsts"},
+		{name: "Missing CSP falls back to X-Frame-Options", page: "https://example.com", modify: func(h http.Header) {
+			h.Del("Content-Security-Policy")
+			h.Set("X-Frame-Options", "SAMEORIGIN")
+		}, score: 80, grade: "B", severity: types.SeverityError, check: "csp"},
+		{name: "No clickjacking protection", page: "https://example.com", modify: func(h http.Header) { h.Set("Content-Security-Policy", "default-src 'self'") }, score: 85, grade: "B", severity: types.SeverityError, check: "framing"},
+		{name: "Unsafe referrer policy", page: "https://example.com", modify: func(h http.Header) { h.Set("Referrer-Policy", "no-referrer, unsafe-url") }, score: 95, grade: "A", severity: types.SeverityWarning, check: "referrer-policy"},
+		{name: "Information leaks", page: "https://example.com", modify: func(h http.Header) {
+			h.Set("Server", "nginx/1.18.0")
+			h.Set("X-Powered-By", "PHP/8.1")
+		}, score: 90, grade: "A", severity: types.SeverityWarning, check: "information-leak"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := secure.Clone()
+			tt.modify(header)
+			pageURL, _ := url.Parse(tt.page)
+
+			got := auditSecurityHeaders(header, pageURL)
+
+			assert.Equal(t, tt.score, got.Score)
+			assert.Equal(t, tt.grade, got.Grade)
+			if tt.check != "" {
+				assert.True(t, hasFinding(got.Findings, tt.severity, tt.check), "expected %s finding in %v", tt.check, got.Findings)
+			}
+		})
+	}
+}
+
+func Test_parseHSTS(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  types.HSTSInfo
+	}{
+		{name: "Preload eligible", value: "max-age=31536000; includeSubDomains; preload", want: types.HSTSInfo{MaxAge: 31536000, IncludeSubDomains: true, Preload: true, PreloadEligible: true}},
+		{name: "Preload without subdomains", value: `max-age="31536000"; preload`, want: types.HSTSInfo{MaxAge: 31536000, Preload: true}},
+		{name: "Max age only", value: "max-age=300", want: types.HSTSInfo{MaxAge: 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, *parseHSTS(tt.value))
+		})
+	}
+}
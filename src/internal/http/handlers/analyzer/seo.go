@@ -0,0 +1,416 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// Recommended lengths, in characters, for the title and meta description
+const (
+	minTitleLength       = 30
+	maxTitleLength       = 60
+	minDescriptionLength = 70
+	maxDescriptionLength = 160
+)
+
+var (
+	requiredOpenGraph   = []string{"og:title", "og:type", "og:image", "og:url"}
+	requiredTwitterCard = []string{"twitter:card", "twitter:title", "twitter:description", "twitter:image"}
+
+	// hreflangRegex matches a language code with an optional region, or x-default
+	hreflangRegex = regexp.MustCompile(`(?i)^([a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?|x-default)$`)
+)
+
+// auditSEO inspects the page metadata that search engines rely on
+func auditSEO(doc *goquery.Document, pageURL *url.URL, header http.Header, opts *analysisOptions) *types.SEOReport {
+	logrus.Debug("Auditing SEO metadata")
+	report := &types.SEOReport{
+		OpenGraph:   make(map[string]string),
+		TwitterCard: make(map[string]string),
+		Findings:    []types.Finding{},
+	}
+
+	auditTitle(doc, report)
+	auditDescription(doc, report)
+	auditCanonical(doc, pageURL, report)
+	auditIndexability(doc, header, report)
+	auditSocialTags(doc, report)
+	auditHreflang(doc, pageURL, report, opts)
+	auditPagination(doc, pageURL, report)
+
+	logrus.Debug("SEO audit completed with ", len(report.Findings), " findings")
+	return report
+}
+
+// addFinding appends a finding to the list
+func addFinding(findings *[]types.Finding, severity, check, format string, args ...interface{}) {
+	*findings = append(*findings, types.Finding{
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func auditTitle(doc *goquery.Document, report *types.SEOReport) {
+	titles := doc.Find("head title")
+	if titles.Length() == 0 {
+		titles = doc.Find("title")
+	}
+	if titles.Length() == 0 {
+		addFinding(&report.Findings, types.SeverityError, "title", "Page has no <title> element")
+		return
+	}
+	if titles.Length() > 1 {
+		addFinding(&report.Findings, types.SeverityWarning, "title", "Page has %d <title> elements", titles.Length())
+	}
+
+	report.Title = strings.TrimSpace(titles.First().Text())
+	report.TitleLength = utf8.RuneCountInString(report.Title)
+	switch {
+	case report.TitleLength == 0:
+		addFinding(&report.Findings, types.SeverityError, "title", "Title is empty")
+	case report.TitleLength < minTitleLength:
+		addFinding(&report.Findings, types.SeverityWarning, "title", "Title is %d characters, shorter than the recommended %d", report.TitleLength, minTitleLength)
+	case report.TitleLength > maxTitleLength:
+		addFinding(&report.Findings, types.SeverityWarning, "title", "Title is %d characters and may be truncated after %d", report.TitleLength, maxTitleLength)
+	}
+}
+
+func auditDescription(doc *goquery.Document, report *types.SEOReport) {
+	descriptions := doc.Find("meta[name]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return strings.EqualFold(s.AttrOr("name", ""), "description")
+	})
+	if descriptions.Length() == 0 {
+		addFinding(&report.Findings, types.SeverityWarning, "meta-description", "Page has no meta description")
+		return
+	}
+	if descriptions.Length() > 1 {
+		addFinding(&report.Findings, types.SeverityWarning, "meta-description", "Page has %d meta descriptions", descriptions.Length())
+	}
+
+	report.MetaDescription = strings.TrimSpace(descriptions.First().AttrOr("content", ""))
+	report.DescriptionLength = utf8.RuneCountInString(report.MetaDescription)
+	switch {
+	case report.DescriptionLength == 0:
+		addFinding(&report.Findings, types.SeverityWarning, "meta-description", "Meta description is empty")
+	case report.DescriptionLength < minDescriptionLength:
+		addFinding(&report.Findings, types.SeverityInfo, "meta-description", "Meta description is %d characters, shorter than the recommended %d", report.DescriptionLength, minDescriptionLength)
+	case report.DescriptionLength > maxDescriptionLength:
+		addFinding(&report.Findings, types.SeverityInfo, "meta-description", "Meta description is %d characters and may be truncated after %d", report.DescriptionLength, maxDescriptionLength)
+	}
+}
+
+func auditCanonical(doc *goquery.Document, pageURL *url.URL, report *types.SEOReport) {
+	canonicals := findLinkRel(doc, "canonical")
+	if canonicals.Length() == 0 {
+		addFinding(&report.Findings, types.SeverityInfo, "canonical", "Page has no canonical link")
+		return
+	}
+	if canonicals.Length() > 1 {
+		addFinding(&report.Findings, types.SeverityError, "canonical", "Page has %d canonical links, search engines may ignore all of them", canonicals.Length())
+	}
+
+	href := strings.TrimSpace(canonicals.First().AttrOr("href", ""))
+	canonical, err := url.Parse(href)
+	if href == "" || err != nil {
+		addFinding(&report.Findings, types.SeverityError, "canonical", "Canonical link %q is not a valid URL", href)
+		return
+	}
+	if !canonical.IsAbs() {
+		addFinding(&report.Findings, types.SeverityWarning, "canonical", "Canonical link %q is relative, an absolute URL is recommended", href)
+	}
+
+	resolved := pageURL.ResolveReference(canonical)
+	report.Canonical = resolved.String()
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		addFinding(&report.Findings, types.SeverityError, "canonical", "Canonical link uses unsupported scheme %q", resolved.Scheme)
+	} else if !strings.EqualFold(resolved.Host, pageURL.Host) {
+		addFinding(&report.Findings, types.SeverityInfo, "canonical", "Canonical link points to another host: %s", resolved.Host)
+	}
+}
+
+func auditIndexability(doc *goquery.Document, header http.Header, report *types.SEOReport) {
+	var directives []string
+	doc.Find("meta[name]").Each(func(i int, s *goquery.Selection) {
+		name := strings.ToLower(s.AttrOr("name", ""))
+		if name == "robots" || name == "googlebot" {
+			content := s.AttrOr("content", "")
+			if report.MetaRobots == "" {
+				report.MetaRobots = content
+			}
+			directives = append(directives, content)
+		}
+	})
+
+	xRobots := header.Values("X-Robots-Tag")
+	report.XRobotsTag = strings.Join(xRobots, ", ")
+	for _, value := range xRobots {
+		// Header values may be scoped to a user agent, e.g. "otherbot: noindex"
+		agent, rest, scoped := strings.Cut(value, ":")
+		agent = strings.ToLower(strings.TrimSpace(agent))
+		if scoped && !strings.Contains(agent, ",") && !isRobotsDirective(agent) {
+			if agent != "googlebot" && agent != "*" {
+				continue
+			}
+			value = rest
+		}
+		directives = append(directives, value)
+	}
+
+	report.Indexable = true
+	followable := true
+	for _, directive := range directives {
+		for _, token := range strings.Split(directive, ",") {
+			switch strings.ToLower(strings.TrimSpace(token)) {
+			case "none":
+				report.Indexable = false
+				followable = false
+			case "noindex":
+				report.Indexable = false
+			case "nofollow":
+				followable = false
+			}
+		}
+	}
+
+	if !report.Indexable {
+		addFinding(&report.Findings, types.SeverityWarning, "robots", "Page is not indexable because of robots directives")
+	}
+	if !followable {
+		addFinding(&report.Findings, types.SeverityInfo, "robots", "Robots directives ask crawlers not to follow links")
+	}
+}
+
+// isRobotsDirective reports whether the token is a known robots directive name
+// rather than a user agent
+func isRobotsDirective(token string) bool {
+	switch token {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+func auditSocialTags(doc *goquery.Document, report *types.SEOReport) {
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		key := strings.ToLower(s.AttrOr("property", s.AttrOr("name", "")))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		switch {
+		case strings.HasPrefix(key, "og:"):
+			report.OpenGraph[key] = content
+		case strings.HasPrefix(key, "twitter:"):
+			report.TwitterCard[key] = content
+		}
+	})
+
+	if missing := missingKeys(report.OpenGraph, requiredOpenGraph, nil); len(missing) > 0 {
+		addFinding(&report.Findings, types.SeverityWarning, "open-graph", "Missing Open Graph properties: %s", strings.Join(missing, ", "))
+	}
+
+	// Twitter falls back to the equivalent Open Graph property when its own tag is missing
+	fallback := map[string]string{
+		"twitter:title":       "og:title",
+		"twitter:description": "og:description",
+		"twitter:image":       "og:image",
+	}
+	if missing := missingKeys(report.TwitterCard, requiredTwitterCard, func(key string) bool {
+		return report.OpenGraph[fallback[key]] != ""
+	}); len(missing) > 0 {
+		addFinding(&report.Findings, types.SeverityInfo, "twitter-card", "Missing Twitter card properties: %s", strings.Join(missing, ", "))
+	}
+}
+
+// missingKeys returns the required keys that have no value and no fallback
+func missingKeys(values map[string]string, required []string, hasFallback func(string) bool) []string {
+	var missing []string
+	for _, key := range required {
+		if values[key] != "" {
+			continue
+		}
+		if hasFallback != nil && hasFallback(key) {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	return missing
+}
+
+func auditHreflang(doc *goquery.Document, pageURL *url.URL, report *types.SEOReport, opts *analysisOptions) {
+	alternates := findHreflang(doc, pageURL)
+	if len(alternates) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	hasSelf := false
+	for _, alt := range alternates {
+		lang := strings.ToLower(alt.Lang)
+		if !hreflangRegex.MatchString(alt.Lang) {
+			addFinding(&report.Findings, types.SeverityError, "hreflang", "Invalid hreflang value %q", alt.Lang)
+		}
+		if seen[lang] {
+			addFinding(&report.Findings, types.SeverityWarning, "hreflang", "Duplicate hreflang value %q", alt.Lang)
+		}
+		seen[lang] = true
+		if sameURL(alt.Href, pageURL.String()) {
+			hasSelf = true
+		}
+	}
+	if !hasSelf {
+		addFinding(&report.Findings, types.SeverityWarning, "hreflang", "hreflang annotations do not include a self-referencing entry")
+	}
+	if !seen["x-default"] {
+		addFinding(&report.Findings, types.SeverityInfo, "hreflang", "hreflang annotations have no x-default entry")
+	}
+
+	if checked := checkReturnLinks(alternates, pageURL, opts); checked < len(alternates) {
+		addFinding(&report.Findings, types.SeverityInfo, "hreflang", "Only the first %d of %d alternates were checked for return links", checked, len(alternates))
+	}
+	for _, alt := range alternates {
+		switch alt.ReturnLink {
+		case types.ReturnLinkMissing:
+			addFinding(&report.Findings, types.SeverityError, "hreflang", "Alternate %s (%s) does not link back to this page", alt.Href, alt.Lang)
+		case types.ReturnLinkUnreachable:
+			addFinding(&report.Findings, types.SeverityWarning, "hreflang", "Alternate %s (%s) could not be fetched to verify its return link", alt.Href, alt.Lang)
+		}
+	}
+	report.Hreflang = alternates
+}
+
+// findHreflang returns the hreflang alternates declared in the document, resolved
+// against base
+func findHreflang(doc *goquery.Document, base *url.URL) []types.HreflangLink {
+	var alternates []types.HreflangLink
+	findLinkRel(doc, "alternate").Each(func(i int, s *goquery.Selection) {
+		lang, ok := s.Attr("hreflang")
+		if !ok {
+			return
+		}
+		href, err := url.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+		alternates = append(alternates, types.HreflangLink{
+			Lang: strings.TrimSpace(lang),
+			Href: base.ResolveReference(href).String(),
+		})
+	})
+	return alternates
+}
+
+// checkReturnLinks fetches the alternate pages, up to the link cap of the
+// analysis, verifies they declare an hreflang link back to the page and
+// returns how many alternates were checked
+func checkReturnLinks(alternates []types.HreflangLink, pageURL *url.URL, opts *analysisOptions) int {
+	checked := len(alternates)
+	if opts.maxLinks > 0 && checked > opts.maxLinks {
+		checked = opts.maxLinks
+		for i := checked; i < len(alternates); i++ {
+			alternates[i].ReturnLink = types.ReturnLinkUnchecked
+		}
+	}
+
+	var waitGroup sync.WaitGroup
+	semaphore := make(chan struct{}, linkCheckConcurrency)
+	for i := range alternates[:checked] {
+		if sameURL(alternates[i].Href, pageURL.String()) {
+			alternates[i].ReturnLink = types.ReturnLinkSelf
+			continue
+		}
+
+		waitGroup.Add(1)
+		go func(alt *types.HreflangLink) {
+			defer waitGroup.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			alt.ReturnLink = fetchReturnLink(alt.Href, pageURL, opts)
+		}(&alternates[i])
+	}
+	waitGroup.Wait()
+	return checked
+}
+
+func fetchReturnLink(alternateURL string, pageURL *url.URL, opts *analysisOptions) string {
+	resp, err := fetchTimedURL(alternateURL, nil, opts)
+	if err != nil {
+		return types.ReturnLinkUnreachable
+	}
+	defer resp.Body.Close()
+
+	doc, err := parseHTML(resp.Body)
+	if err != nil {
+		return types.ReturnLinkUnreachable
+	}
+
+	base := resp.Request.URL
+	for _, alt := range findHreflang(doc, base) {
+		if sameURL(alt.Href, pageURL.String()) {
+			return types.ReturnLinkOK
+		}
+	}
+	return types.ReturnLinkMissing
+}
+
+func auditPagination(doc *goquery.Document, pageURL *url.URL, report *types.SEOReport) {
+	for _, rel := range []string{"next", "prev"} {
+		links := findLinkRel(doc, rel)
+		if links.Length() == 0 {
+			continue
+		}
+		if links.Length() > 1 {
+			addFinding(&report.Findings, types.SeverityWarning, "pagination", "Page has %d rel=%s links", links.Length(), rel)
+		}
+
+		href, err := url.Parse(strings.TrimSpace(links.First().AttrOr("href", "")))
+		if err != nil || href.String() == "" {
+			addFinding(&report.Findings, types.SeverityError, "pagination", "rel=%s link has an invalid href", rel)
+			continue
+		}
+		resolved := pageURL.ResolveReference(href).String()
+		if sameURL(resolved, pageURL.String()) {
+			addFinding(&report.Findings, types.SeverityWarning, "pagination", "rel=%s link points to the page itself", rel)
+		}
+		if rel == "next" {
+			report.Next = resolved
+		} else {
+			report.Prev = resolved
+		}
+	}
+}
+
+// findLinkRel selects <link> elements whose rel attribute contains the given value
+func findLinkRel(doc *goquery.Document, rel string) *goquery.Selection {
+	return doc.Find("link[rel]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		for _, value := range strings.Fields(s.AttrOr("rel", "")) {
+			if strings.EqualFold(value, rel) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// sameURL compares two absolute URLs ignoring fragments, host case and a
+// trailing slash on the path
+func sameURL(a, b string) bool {
+	normalize := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		u.Fragment = ""
+		u.Host = strings.ToLower(u.Host)
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		return u.String()
+	}
+	return normalize(a) == normalize(b)
+}
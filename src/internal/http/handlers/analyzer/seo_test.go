@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// hasFinding reports whether a finding with the given check and severity exists
+func hasFinding(findings []types.Finding, severity, check string) bool {
+	for _, f := range findings {
+		if f.Severity == severity && f.Check == check {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_auditSEO(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/page")
+	tests := []struct {
+		name      string
+		html      string
+		header    http.Header
+		severity  string
+		check     string
+		indexable bool
+	}{
+		{name: "Missing title", html: "<html><head></head></html>", severity: types.SeverityError, check: "title", indexable: true},
+		{name: "Short title", html: "<html><head><title>Hi</title></head></html>", severity: types.SeverityWarning, check: "title", indexable: true},
+		{name: "Duplicate titles", html: "<html><head><title>A title that is long enough for search</title><title>Another</title></head></html>", severity: types.SeverityWarning, check: "title", indexable: true},
+		{name: "Missing description", html: "<html><head></head></html>", severity: types.SeverityWarning, check: "meta-description", indexable: true},
+		{name: "Multiple canonicals", html: `<html><head><link rel="canonical" href="https://example.com/a"><link rel="canonical" href="https://example.com/b"></head></html>`, severity: types.SeverityError, check: "canonical", indexable: true},
+		{name: "Relative canonical", html: `<html><head><link rel="canonical" href="/page"></head></html>`, severity: types.SeverityWarning, check: "canonical", indexable: true},
+		{name: "Meta noindex", html: `<html><head><meta name="robots" content="noindex, follow"></head></html>`, severity: types.SeverityWarning, check: "robots", indexable: false},
+		{name: "Header noindex", html: "<html></html>", header: http.Header{"X-Robots-Tag": {"noindex"}}, severity: types.SeverityWarning, check: "robots", indexable: false},
+		{name: "Header scoped to other bot", html: "<html></html>", header: http.Header{"X-Robots-Tag": {"otherbot: noindex"}}, indexable: true},
+		{name: "Missing Open Graph", html: `<html><head><meta property="og:title" content="T"></head></html>`, severity: types.SeverityWarning, check: "open-graph", indexable: true},
+		{name: "Self pagination", html: `<html><head><link rel="next" href="/page"></head></html>`, severity: types.SeverityWarning, check: "pagination", indexable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			got := auditSEO(doc, pageURL, header, defaultOptions(pageURL.String()))
+			assert.Equal(t, tt.indexable, got.Indexable)
+			if tt.check != "" {
+				assert.True(t, hasFinding(got.Findings, tt.severity, tt.check), "expected %s %s finding in %v", tt.severity, tt.check, got.Findings)
+			}
+		})
+	}
+}
+
+func Test_auditSocialTags_TwitterFallback(t *testing.T) {
+	html := `<html><head>
+		<meta property="og:title" content="T"><meta property="og:type" content="website">
+		<meta property="og:image" content="https://example.com/i.png"><meta property="og:url" content="https://example.com/">
+		<meta property="og:description" content="D"><meta name="twitter:card" content="summary">
+	</head></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	report := &types.SEOReport{OpenGraph: map[string]string{}, TwitterCard: map[string]string{}}
+
+	auditSocialTags(doc, report)
+
+	assert.Equal(t, "summary", report.TwitterCard["twitter:card"])
+	assert.Empty(t, report.Findings)
+}
+
+func Test_auditHreflang(t *testing.T) {
+	var pageURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fr", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><link rel="alternate" hreflang="en" href="%s"></head></html>`, pageURL)
+	})
+	mux.HandleFunc("/de", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head></head></html>`)
+	})
+	mux.HandleFunc("/es", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	pageURL = ts.URL + "/en"
+
+	html := `<html><head>
+		<link rel="alternate" hreflang="en" href="/en">
+		<link rel="alternate" hreflang="fr" href="/fr">
+		<link rel="alternate" hreflang="de" href="/de">
+		<link rel="alternate" hreflang="es" href="/es">
+		<link rel="alternate" hreflang="english" href="/en">
+	</head></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	parsed, _ := url.Parse(pageURL)
+	report := &types.SEOReport{}
+
+	auditHreflang(doc, parsed, report, defaultOptions(pageURL))
+
+	statuses := make(map[string]string)
+	for _, alt := range report.Hreflang {
+		statuses[alt.Lang] = alt.ReturnLink
+	}
+	assert.Equal(t, types.ReturnLinkSelf, statuses["en"])
+	assert.Equal(t, types.ReturnLinkOK, statuses["fr"])
+	assert.Equal(t, types.ReturnLinkMissing, statuses["de"])
+	assert.Equal(t, types.ReturnLinkUnreachable, statuses["es"])
+	assert.True(t, hasFinding(report.Findings, types.SeverityError, "hreflang"))
+	assert.True(t, hasFinding(report.Findings, types.SeverityInfo, "hreflang"))
+}
+
+func Test_auditHreflang_Options(t *testing.T) {
+	var userAgents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		fmt.Fprint(w, `<html><head></head></html>`)
+	}))
+	defer ts.Close()
+	pageURL := ts.URL + "/en"
+
+	html := `<html><head>
+		<link rel="alternate" hreflang="en" href="/en">
+		<link rel="alternate" hreflang="fr" href="/fr">
+		<link rel="alternate" hreflang="de" href="/de">
+	</head></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	parsed, _ := url.Parse(pageURL)
+	opts := defaultOptions(pageURL)
+	opts.userAgent = "hreflang-checker/2.0"
+	opts.maxLinks = 2
+	report := &types.SEOReport{}
+
+	auditHreflang(doc, parsed, report, opts)
+
+	assert.Equal(t, []string{"hreflang-checker/2.0"}, userAgents)
+	if assert.Len(t, report.Hreflang, 3) {
+		assert.Equal(t, types.ReturnLinkMissing, report.Hreflang[1].ReturnLink)
+		assert.Equal(t, types.ReturnLinkUnchecked, report.Hreflang[2].ReturnLink)
+	}
+}
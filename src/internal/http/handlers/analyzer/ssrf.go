@@ -0,0 +1,243 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/config"
+)
+
+// blockedPrefixes are refused unless explicitly allowed: loopback, private,
+// link-local (including cloud metadata endpoints), carrier-grade NAT and
+// reserved ranges
+var blockedPrefixes = mustParsePrefixes(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b:1::/48",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+var (
+	nat64Prefix     = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")
+)
+
+// BlockedTargetError is returned when the analyzer refuses to connect to a host
+type BlockedTargetError struct {
+	Host   string
+	IP     string
+	Reason string
+}
+
+func (e *BlockedTargetError) Error() string {
+	if e.IP == "" {
+		return fmt.Sprintf("refusing to connect to %s: %s", e.Host, e.Reason)
+	}
+	return fmt.Sprintf("refusing to connect to %s (%s): %s", e.Host, e.IP, e.Reason)
+}
+
+// ssrfGuard decides which hosts and addresses may be dialed
+type ssrfGuard struct {
+	allowCIDRs []netip.Prefix
+	denyCIDRs  []netip.Prefix
+	allowHosts []string
+	denyHosts  []string
+}
+
+var (
+	guardMu sync.RWMutex
+	guard   = &ssrfGuard{}
+)
+
+// ConfigureSSRF sets the allow and deny lists applied to every connection the analyzer makes
+func ConfigureSSRF(cfg config.SSRF) error {
+	g := &ssrfGuard{allowHosts: normalizeHostPatterns(cfg.AllowHosts), denyHosts: normalizeHostPatterns(cfg.DenyHosts)}
+	var err error
+	if g.allowCIDRs, err = parsePrefixes(cfg.AllowCIDRs); err != nil {
+		return err
+	}
+	if g.denyCIDRs, err = parsePrefixes(cfg.DenyCIDRs); err != nil {
+		return err
+	}
+
+	guardMu.Lock()
+	guard = g
+	guardMu.Unlock()
+	// Pooled connections were checked against the previous lists
+	httpClient.CloseIdleConnections()
+	logrus.WithFields(logrus.Fields{
+		"allowCIDRs": cfg.AllowCIDRs,
+		"denyCIDRs":  cfg.DenyCIDRs,
+		"allowHosts": cfg.AllowHosts,
+		"denyHosts":  cfg.DenyHosts,
+	}).Info("SSRF guard configured")
+	return nil
+}
+
+// guardedTransport is the default transport with its dialer replaced by the
+// guarded one and its proxy taken from the network config
+func guardedTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = guardedDialContext
+	transport.Proxy = proxyForRequest
+	return transport
+}
+
+func currentGuard() *ssrfGuard {
+	guardMu.RLock()
+	defer guardMu.RUnlock()
+	return guard
+}
+
+// guardedDialContext checks the hostname before resolution and every resolved
+// address right before the connection is made, so neither DNS rebinding nor a
+// redirect can reach a blocked address
+func guardedDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	g, n := currentGuard(), currentNetwork()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: n.resolver}
+	// The configured HTTP proxy is trusted, wherever it is
+	if n.proxy != nil && n.proxy.Scheme != "socks5" && addr == n.proxyAddr {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchesHostPattern(host, g.denyHosts) {
+		return nil, &BlockedTargetError{Host: host, Reason: "host is denied"}
+	}
+	hostAllowed := matchesHostPattern(host, g.allowHosts)
+	if proxyURL := n.proxyFor(host); proxyURL != nil && proxyURL.Scheme == "socks5" {
+		return dialSOCKS(ctx, n, g, network, host, port, hostAllowed)
+	}
+	if static, ok := n.hosts[host]; ok {
+		addr = net.JoinHostPort(static.String(), port)
+	}
+
+	dialer.Control = func(network, address string, _ syscall.RawConn) error {
+		ip, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return &BlockedTargetError{Host: host, IP: address, Reason: "address could not be parsed"}
+		}
+		return g.checkAddr(host, ip.Addr(), hostAllowed)
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func (g *ssrfGuard) checkAddr(host string, addr netip.Addr, hostAllowed bool) error {
+	addr = addr.Unmap()
+	// NAT64 and 6to4 addresses reach the IPv4 address they embed, which gets the IPv4 rules
+	embedded, hasEmbedded := embeddedIPv4(addr)
+	switch {
+	case containsAddr(g.denyCIDRs, addr), hasEmbedded && containsAddr(g.denyCIDRs, embedded):
+		return &BlockedTargetError{Host: host, IP: addr.String(), Reason: "address is in a denied range"}
+	case hostAllowed, containsAddr(g.allowCIDRs, addr), hasEmbedded && containsAddr(g.allowCIDRs, embedded):
+		return nil
+	case containsAddr(blockedPrefixes, addr), hasEmbedded && containsAddr(blockedPrefixes, embedded):
+		logrus.Warn("Blocked connection to internal address ", addr, " for ", host)
+		return &BlockedTargetError{Host: host, IP: addr.String(), Reason: "address is private, loopback, link-local or reserved"}
+	}
+	return nil
+}
+
+// embeddedIPv4 returns the IPv4 address inside a well-known NAT64 (64:ff9b::/96)
+// or 6to4 (2002::/16) address
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	bytes := addr.As16()
+	switch {
+	case !addr.Is6():
+		return netip.Addr{}, false
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[12:16])), true
+	case sixToFourPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[2:6])), true
+	}
+	return netip.Addr{}, false
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHostPattern matches exact hostnames, and subdomains for patterns
+// written as "*.example.com" or ".example.com"
+func matchesHostPattern(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if base, ok := strings.CutPrefix(pattern, "."); ok {
+			if host == base || strings.HasSuffix(host, pattern) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHostPatterns(hosts []string) []string {
+	patterns := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+		if host != "" {
+			patterns = append(patterns, strings.TrimPrefix(host, "*"))
+		}
+	}
+	return patterns
+}
+
+// parsePrefixes parses CIDRs, accepting bare addresses as single-address ranges
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(cidr); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func mustParsePrefixes(cidrs ...string) []netip.Prefix {
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/config"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// TestMain allows loopback connections so the package's tests can reach their
+// httptest servers through the guarded client
+func TestMain(m *testing.M) {
+	if err := ConfigureSSRF(config.SSRF{AllowCIDRs: []string{"127.0.0.0/8", "::1/128"}}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// withSSRF replaces the SSRF guard for the duration of the test
+func withSSRF(t *testing.T, cfg config.SSRF) {
+	previous := currentGuard()
+	assert.NoError(t, ConfigureSSRF(cfg))
+	t.Cleanup(func() {
+		guardMu.Lock()
+		guard = previous
+		guardMu.Unlock()
+		httpClient.CloseIdleConnections()
+	})
+}
+
+func Test_fetchURL_SSRF(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		w.Write([]byte("<html><title>Internal</title></html>"))
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	localhostURL := "http://localhost:" + port + "/"
+
+	tests := []struct {
+		name    string
+		ssrf    config.SSRF
+		url     string
+		blocked bool
+	}{
+		{name: "Loopback address blocked by default", url: ts.URL, blocked: true},
+		{name: "Localhost blocked by default", url: localhostURL, blocked: true},
+		{name: "Allowed CIDR", ssrf: config.SSRF{AllowCIDRs: []string{"127.0.0.1/32"}}, url: ts.URL},
+		{name: "Allowed host", ssrf: config.SSRF{AllowHosts: []string{"127.0.0.1"}}, url: ts.URL},
+		{name: "Redirect to a blocked host", ssrf: config.SSRF{AllowHosts: []string{"127.0.0.1"}}, url: ts.URL + "/redirect?to=" + url.QueryEscape(localhostURL), blocked: true},
+		{name: "Denied CIDR overrides allowed host", ssrf: config.SSRF{AllowHosts: []string{"127.0.0.1"}, DenyCIDRs: []string{"127.0.0.1"}}, url: ts.URL, blocked: true},
+		{name: "Denied host overrides allowed CIDR", ssrf: config.SSRF{AllowCIDRs: []string{"127.0.0.0/8", "::1/128"}, DenyHosts: []string{"LOCALHOST."}}, url: localhostURL, blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withSSRF(t, tt.ssrf)
+			resp, err := fetchURL(tt.url)
+			if tt.blocked {
+				var blockedErr *BlockedTargetError
+				assert.ErrorAs(t, err, &blockedErr)
+				return
+			}
+			assert.NoError(t, err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func Test_ssrfGuard_checkAddr(t *testing.T) {
+	withSSRF(t, config.SSRF{AllowCIDRs: []string{"10.1.0.0/16"}, DenyCIDRs: []string{"203.0.113.0/24"}})
+	g := currentGuard()
+
+	tests := []struct {
+		addr        string
+		hostAllowed bool
+		blocked     bool
+	}{
+		{addr: "93.184.216.34"},
+		{addr: "2606:2800:220:1::1"},
+		{addr: "169.254.169.254", blocked: true},
+		{addr: "::ffff:169.254.169.254", blocked: true},
+		{addr: "127.0.0.1", blocked: true},
+		{addr: "0.0.0.0", blocked: true},
+		{addr: "192.168.1.1", blocked: true},
+		{addr: "172.20.0.1", blocked: true},
+		{addr: "100.64.0.1", blocked: true},
+		{addr: "::1", blocked: true},
+		{addr: "fd00::1", blocked: true},
+		{addr: "fe80::1", blocked: true},
+		{addr: "10.2.0.1", blocked: true},
+		{addr: "10.1.0.1"},
+		{addr: "192.168.1.1", hostAllowed: true},
+		{addr: "203.0.113.5", blocked: true},
+		{addr: "203.0.113.5", hostAllowed: true, blocked: true},
+		{addr: "64:ff9b::7f00:1", blocked: true},
+		{addr: "64:ff9b::a9fe:a9fe", blocked: true},
+		{addr: "64:ff9b::5db8:d822"},
+		{addr: "64:ff9b::a01:1"},
+		{addr: "64:ff9b::cb00:7105", hostAllowed: true, blocked: true},
+		{addr: "64:ff9b:1::5db8:d822", blocked: true},
+		{addr: "2002:7f00:1::", blocked: true},
+		{addr: "2002:c0a8:101::1", blocked: true},
+		{addr: "2002:5db8:d822::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := g.checkAddr("example.com", netip.MustParseAddr(tt.addr), tt.hostAllowed)
+			assert.Equal(t, tt.blocked, err != nil, err)
+		})
+	}
+}
+
+func Test_matchesHostPattern(t *testing.T) {
+	patterns := normalizeHostPatterns([]string{"Intranet.Example.com.", "*.corp.example", ".svc.local", " "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "intranet.example.com", want: true},
+		{host: "www.intranet.example.com", want: false},
+		{host: "a.corp.example", want: true},
+		{host: "a.b.corp.example", want: true},
+		{host: "corp.example", want: true},
+		{host: "evilcorp.example", want: false},
+		{host: "api.svc.local", want: true},
+		{host: "example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchesHostPattern(tt.host, patterns))
+		})
+	}
+}
+
+func Test_ConfigureSSRF_InvalidCIDR(t *testing.T) {
+	withSSRF(t, config.SSRF{})
+	assert.Error(t, ConfigureSSRF(config.SSRF{DenyCIDRs: []string{"10.0.0.0/33"}}))
+	assert.Error(t, ConfigureSSRF(config.SSRF{AllowCIDRs: []string{"intranet"}}))
+}
+
+func Test_GetResults_BlockedTarget(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><title>Internal</title></html>"))
+	}))
+	defer ts.Close()
+	withSSRF(t, config.SSRF{})
+
+	body, _ := json.Marshal(types.RequestPayload{URL: ts.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	GetResults(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "refusing to connect")
+}
@@ -0,0 +1,393 @@
+package analyzer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// schemaRule lists the properties expected on a schema.org type
+type schemaRule struct {
+	Extends     string   `json:"extends"`
+	Required    []string `json:"required"`
+	Recommended []string `json:"recommended"`
+}
+
+//go:embed schemaorg.json
+var schemaRulesJSON []byte
+
+var schemaRules = mustLoadSchemaRules(schemaRulesJSON)
+
+var schemaPrefixes = []string{"https://schema.org/", "http://schema.org/", "schema:"}
+
+func mustLoadSchemaRules(data []byte) map[string]schemaRule {
+	rules := make(map[string]schemaRule)
+	if err := json.Unmarshal(data, &rules); err != nil {
+		panic(fmt.Sprintf("invalid schema.org rules: %v", err))
+	}
+	resolved := make(map[string]schemaRule, len(rules))
+	for name := range rules {
+		resolveSchemaRule(name, rules, resolved, nil)
+	}
+	return resolved
+}
+
+// resolveSchemaRule stores the rule for name with the properties of the types
+// it extends added in front of its own, following extends to any depth. path
+// holds the types being resolved, a type extending one of them is a cycle.
+func resolveSchemaRule(name string, rules, resolved map[string]schemaRule, path []string) schemaRule {
+	if rule, ok := resolved[name]; ok {
+		return rule
+	}
+	for _, seen := range path {
+		if seen == name {
+			panic(fmt.Sprintf("invalid schema.org rules: %s extends itself through %s", name, strings.Join(append(path, name), " -> ")))
+		}
+	}
+	rule, ok := rules[name]
+	if !ok {
+		panic(fmt.Sprintf("invalid schema.org rules: %s extends unknown type %s", path[len(path)-1], name))
+	}
+	if rule.Extends != "" {
+		parent := resolveSchemaRule(rule.Extends, rules, resolved, append(path, name))
+		rule.Required = mergeProperties(parent.Required, rule.Required)
+		rule.Recommended = mergeProperties(parent.Recommended, rule.Recommended)
+	}
+	resolved[name] = rule
+	return rule
+}
+
+// mergeProperties returns the inherited properties followed by the own ones not already among them
+func mergeProperties(inherited, own []string) []string {
+	merged := append([]string{}, inherited...)
+	seen := toSet(inherited...)
+	for _, property := range own {
+		if !seen[property] {
+			merged = append(merged, property)
+		}
+	}
+	return merged
+}
+
+// extractStructuredData collects JSON-LD, microdata and RDFa from the document
+// and validates the schema.org types it knows about
+func extractStructuredData(doc *goquery.Document) *types.StructuredDataReport {
+	logrus.Debug("Extracting structured data")
+	report := &types.StructuredDataReport{
+		JSONLD:    []interface{}{},
+		Microdata: extractMicrodata(doc),
+		RDFa:      extractRDFa(doc),
+		Findings:  []types.Finding{},
+	}
+
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		if !strings.EqualFold(strings.TrimSpace(s.AttrOr("type", "")), "application/ld+json") {
+			return
+		}
+		var block interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &block); err != nil {
+			addFinding(&report.Findings, types.SeverityError, "json-ld", "JSON-LD block %d could not be parsed: %v", len(report.JSONLD)+1, err)
+			return
+		}
+		report.JSONLD = append(report.JSONLD, block)
+	})
+
+	for i, block := range report.JSONLD {
+		for _, node := range jsonLDNodes(block) {
+			if _, ok := node["@context"]; !ok && !isGraphMember(block, node) {
+				addFinding(&report.Findings, types.SeverityWarning, "json-ld", "JSON-LD block %d has a node without @context", i+1)
+			}
+			validateItem(jsonLDItem(node), fmt.Sprintf("JSON-LD block %d", i+1), &report.Findings)
+		}
+	}
+	for i, item := range report.Microdata {
+		validateItem(item, fmt.Sprintf("Microdata item %d", i+1), &report.Findings)
+	}
+	for i, item := range report.RDFa {
+		validateItem(item, fmt.Sprintf("RDFa item %d", i+1), &report.Findings)
+	}
+
+	logrus.Debug("Structured data extracted: ", len(report.JSONLD), " JSON-LD blocks, ", len(report.Microdata), " microdata items, ", len(report.RDFa), " RDFa items")
+	return report
+}
+
+// jsonLDNodes returns the top-level nodes of a JSON-LD block, expanding arrays and @graph
+func jsonLDNodes(block interface{}) []map[string]interface{} {
+	var nodes []map[string]interface{}
+	switch v := block.(type) {
+	case []interface{}:
+		for _, entry := range v {
+			nodes = append(nodes, jsonLDNodes(entry)...)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDNodes(graph)
+		}
+		nodes = append(nodes, v)
+	}
+	return nodes
+}
+
+// isGraphMember reports whether node sits inside an @graph whose object carries
+// the @context, anywhere in block
+func isGraphMember(block interface{}, node map[string]interface{}) bool {
+	switch v := block.(type) {
+	case []interface{}:
+		for _, entry := range v {
+			if isGraphMember(entry, node) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		graph, hasGraph := v["@graph"]
+		if _, hasContext := v["@context"]; !hasGraph || !hasContext {
+			return false
+		}
+		for _, member := range jsonLDNodes(graph) {
+			// Maps are compared by identity, an equal node elsewhere is not a member
+			if reflect.ValueOf(member).Pointer() == reflect.ValueOf(node).Pointer() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonLDItem converts a JSON-LD node into the item shape used for validation
+func jsonLDItem(node map[string]interface{}) types.StructuredDataItem {
+	item := types.StructuredDataItem{Properties: make(map[string][]interface{})}
+	for key, value := range node {
+		switch key {
+		case "@type":
+			item.Type = append(item.Type, stringValues(value)...)
+		case "@id":
+			item.ID, _ = value.(string)
+		default:
+			if strings.HasPrefix(key, "@") {
+				continue
+			}
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+			for _, v := range values {
+				if nested, ok := v.(map[string]interface{}); ok {
+					item.Properties[key] = append(item.Properties[key], jsonLDItem(nested))
+				} else {
+					item.Properties[key] = append(item.Properties[key], v)
+				}
+			}
+		}
+	}
+	return item
+}
+
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// validateItem checks an item, and every nested item, against the schema.org rules
+func validateItem(item types.StructuredDataItem, location string, findings *[]types.Finding) {
+	for _, typeName := range item.Type {
+		rule, ok := schemaRules[trimSchemaPrefix(typeName)]
+		if !ok {
+			continue
+		}
+		name := trimSchemaPrefix(typeName)
+		if missing := missingProperties(item, rule.Required); len(missing) > 0 {
+			addFinding(findings, types.SeverityError, "schema-org", "%s: %s is missing required properties: %s", location, name, strings.Join(missing, ", "))
+		}
+		if missing := missingProperties(item, rule.Recommended); len(missing) > 0 {
+			addFinding(findings, types.SeverityInfo, "schema-org", "%s: %s is missing recommended properties: %s", location, name, strings.Join(missing, ", "))
+		}
+	}
+
+	keys := make([]string, 0, len(item.Properties))
+	for key := range item.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range item.Properties[key] {
+			if nested, ok := value.(types.StructuredDataItem); ok {
+				validateItem(nested, location+" > "+key, findings)
+			}
+		}
+	}
+}
+
+func missingProperties(item types.StructuredDataItem, properties []string) []string {
+	var missing []string
+	for _, property := range properties {
+		if !hasPropertyValue(item.Properties[property]) {
+			missing = append(missing, property)
+		}
+	}
+	return missing
+}
+
+func hasPropertyValue(values []interface{}) bool {
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func trimSchemaPrefix(name string) string {
+	for _, prefix := range schemaPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+// extractMicrodata returns the top-level microdata items in the document
+func extractMicrodata(doc *goquery.Document) []types.StructuredDataItem {
+	items := []types.StructuredDataItem{}
+	doc.Find("[itemscope]").Each(func(i int, s *goquery.Selection) {
+		if _, isProperty := s.Attr("itemprop"); isProperty {
+			return
+		}
+		items = append(items, microdataItem(s))
+	})
+	return items
+}
+
+func microdataItem(scope *goquery.Selection) types.StructuredDataItem {
+	item := types.StructuredDataItem{
+		Type:       strings.Fields(scope.AttrOr("itemtype", "")),
+		ID:         scope.AttrOr("itemid", ""),
+		Properties: make(map[string][]interface{}),
+	}
+
+	scope.Find("[itemprop]").Each(func(i int, s *goquery.Selection) {
+		// Only properties whose nearest enclosing item is this scope belong to it
+		if s.Parent().Closest("[itemscope]").Get(0) != scope.Get(0) {
+			return
+		}
+
+		var value interface{}
+		if _, nested := s.Attr("itemscope"); nested {
+			value = microdataItem(s)
+		} else {
+			value = microdataValue(s)
+		}
+		for _, name := range strings.Fields(s.AttrOr("itemprop", "")) {
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	})
+	return item
+}
+
+// microdataValue returns the property value as defined by the microdata spec
+func microdataValue(s *goquery.Selection) string {
+	switch goquery.NodeName(s) {
+	case "meta":
+		return s.AttrOr("content", "")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return s.AttrOr("src", "")
+	case "a", "area", "link":
+		return s.AttrOr("href", "")
+	case "object":
+		return s.AttrOr("data", "")
+	case "data", "meter":
+		return s.AttrOr("value", "")
+	case "time":
+		if datetime, ok := s.Attr("datetime"); ok {
+			return datetime
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
+
+// extractRDFa returns the typed RDFa resources in the document. Properties
+// outside any typed resource are grouped into a final untyped item.
+func extractRDFa(doc *goquery.Document) []types.StructuredDataItem {
+	items := []types.StructuredDataItem{}
+	doc.Find("[typeof]").Each(func(i int, s *goquery.Selection) {
+		if _, isProperty := s.Attr("property"); isProperty && s.Parent().Closest("[typeof]").Length() > 0 {
+			return
+		}
+		items = append(items, rdfaItem(s))
+	})
+
+	loose := types.StructuredDataItem{Type: []string{}, Properties: make(map[string][]interface{})}
+	doc.Find("[property]").Each(func(i int, s *goquery.Selection) {
+		if s.Parent().Closest("[typeof]").Length() > 0 {
+			return
+		}
+		if _, typed := s.Attr("typeof"); typed {
+			return
+		}
+		for _, name := range strings.Fields(s.AttrOr("property", "")) {
+			loose.Properties[name] = append(loose.Properties[name], rdfaValue(s))
+		}
+	})
+	if len(loose.Properties) > 0 {
+		items = append(items, loose)
+	}
+	return items
+}
+
+func rdfaItem(scope *goquery.Selection) types.StructuredDataItem {
+	item := types.StructuredDataItem{
+		Type:       strings.Fields(scope.AttrOr("typeof", "")),
+		ID:         scope.AttrOr("resource", scope.AttrOr("about", "")),
+		Properties: make(map[string][]interface{}),
+	}
+
+	scope.Find("[property]").Each(func(i int, s *goquery.Selection) {
+		if s.Parent().Closest("[typeof]").Get(0) != scope.Get(0) {
+			return
+		}
+
+		var value interface{}
+		if _, nested := s.Attr("typeof"); nested {
+			value = rdfaItem(s)
+		} else {
+			value = rdfaValue(s)
+		}
+		for _, name := range strings.Fields(s.AttrOr("property", "")) {
+			name = trimSchemaPrefix(name)
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	})
+	return item
+}
+
+func rdfaValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "resource", "href", "src"} {
+		if value, ok := s.Attr(attr); ok {
+			return value
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
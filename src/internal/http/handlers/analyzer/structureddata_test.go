@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_extractStructuredData_JSONLD(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		blocks   int
+		severity string
+		message  string
+	}{
+		{
+			name:   "Valid Organization",
+			html:   `<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Organization", "name": "Acme", "url": "https://acme.test", "logo": "l.png", "sameAs": ["x"], "contactPoint": {"@type": "ContactPoint"}}</script>`,
+			blocks: 1,
+		},
+		{
+			name:     "Parse error",
+			html:     `<script type="application/ld+json">{"@type": "Product",}</script>`,
+			blocks:   0,
+			severity: types.SeverityError,
+			message:  "could not be parsed",
+		},
+		{
+			name:     "Product missing name",
+			html:     `<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Product", "sku": "1"}</script>`,
+			blocks:   1,
+			severity: types.SeverityError,
+			message:  "Product is missing required properties: name",
+		},
+		{
+			name:     "Graph with nested breadcrumb items",
+			html:     `<script type="application/ld+json">{"@context": "https://schema.org", "@graph": [{"@type": "BreadcrumbList", "itemListElement": [{"@type": "ListItem", "name": "Home"}]}]}</script>`,
+			blocks:   1,
+			severity: types.SeverityError,
+			message:  "itemListElement: ListItem is missing required properties: position",
+		},
+		{
+			name:     "Article subtype",
+			html:     `<script type="application/ld+json">{"@context": "https://schema.org", "@type": "NewsArticle"}</script>`,
+			blocks:   1,
+			severity: types.SeverityError,
+			message:  "NewsArticle is missing required properties: headline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.html + "</head></html>"))
+			got := extractStructuredData(doc)
+			assert.Len(t, got.JSONLD, tt.blocks)
+			if tt.message == "" {
+				assert.Empty(t, got.Findings)
+				return
+			}
+			found := false
+			for _, f := range got.Findings {
+				if f.Severity == tt.severity && strings.Contains(f.Message, tt.message) {
+					found = true
+				}
+			}
+			assert.True(t, found, "expected finding %q in %v", tt.message, got.Findings)
+		})
+	}
+}
+
+func Test_extractMicrodata(t *testing.T) {
+	html := `<div itemscope itemtype="https://schema.org/Product">
+		<span itemprop="name">Widget</span>
+		<img itemprop="image" src="/w.png">
+		<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+			<meta itemprop="price" content="9.99">
+			<span itemprop="name">Ignored for product</span>
+		</div>
+	</div>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	items := extractMicrodata(doc)
+
+	assert.Len(t, items, 1)
+	assert.Equal(t, []string{"https://schema.org/Product"}, items[0].Type)
+	assert.Equal(t, []interface{}{"Widget"}, items[0].Properties["name"])
+	assert.Equal(t, []interface{}{"/w.png"}, items[0].Properties["image"])
+	offer := items[0].Properties["offers"][0].(types.StructuredDataItem)
+	assert.Equal(t, []interface{}{"9.99"}, offer.Properties["price"])
+
+	var findings []types.Finding
+	validateItem(items[0], "Microdata item 1", &findings)
+	assert.True(t, hasFinding(findings, types.SeverityError, "schema-org"), "Offer should be missing priceCurrency")
+}
+
+func Test_extractRDFa(t *testing.T) {
+	html := `<html><head><meta property="og:title" content="Page"></head><body>
+		<div vocab="https://schema.org/" typeof="Article">
+			<h1 property="headline">News</h1>
+			<div property="author" typeof="Person"><span property="name">Jane</span></div>
+		</div></body></html>`
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	items := extractRDFa(doc)
+
+	assert.Len(t, items, 2)
+	assert.Equal(t, []string{"Article"}, items[0].Type)
+	assert.Equal(t, []interface{}{"News"}, items[0].Properties["headline"])
+	author := items[0].Properties["author"][0].(types.StructuredDataItem)
+	assert.Equal(t, []interface{}{"Jane"}, author.Properties["name"])
+	assert.Equal(t, []interface{}{"Page"}, items[1].Properties["og:title"])
+}
+
+func Test_mustLoadSchemaRules(t *testing.T) {
+	rules := mustLoadSchemaRules([]byte(`{
+		"Thing": {"required": ["name"], "recommended": ["url"]},
+		"CreativeWork": {"extends": "Thing", "recommended": ["author", "url"]},
+		"Article": {"extends": "CreativeWork", "required": ["headline"]},
+		"NewsArticle": {"extends": "Article"}
+	}`))
+
+	assert.Equal(t, []string{"name", "headline"}, rules["NewsArticle"].Required)
+	assert.Equal(t, []string{"url", "author"}, rules["NewsArticle"].Recommended)
+	assert.Equal(t, []string{"name"}, rules["CreativeWork"].Required)
+	assert.Equal(t, []string{"url"}, rules["Thing"].Recommended)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "Cycle", data: `{"A": {"extends": "B"}, "B": {"extends": "C"}, "C": {"extends": "A"}}`, wantErr: "extends itself"},
+		{name: "Self", data: `{"A": {"extends": "A"}}`, wantErr: "A extends itself through A -> A"},
+		{name: "Unknown parent", data: `{"A": {"extends": "Missing"}}`, wantErr: "A extends unknown type Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				assert.Contains(t, fmt.Sprint(recover()), tt.wantErr)
+			}()
+			mustLoadSchemaRules([]byte(tt.data))
+		})
+	}
+}
+
+func Test_isGraphMember(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		want  []bool
+	}{
+		{name: "Graph with context", block: `{"@context": "https://schema.org", "@graph": [{"@type": "Organization"}, {"@type": "WebSite"}]}`, want: []bool{true, true}},
+		{name: "Graph without context", block: `{"@graph": [{"@type": "Organization"}]}`, want: []bool{false}},
+		{name: "Graph in an array", block: `[{"@context": "https://schema.org", "@graph": [{"@type": "Organization"}]}, {"@type": "WebSite"}]`, want: []bool{true, false}},
+		{name: "Top-level node", block: `{"@context": "https://schema.org", "@type": "Organization"}`, want: []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var block interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tt.block), &block))
+			var got []bool
+			for _, node := range jsonLDNodes(block) {
+				got = append(got, isGraphMember(block, node))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// An equal node outside the graph is not a member
+	var block interface{}
+	json.Unmarshal([]byte(`{"@context": "https://schema.org", "@graph": [{"@type": "Organization"}]}`), &block)
+	assert.False(t, isGraphMember(block, map[string]interface{}{"@type": "Organization"}))
+}
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// Targets the request phase histogram is labeled with
+const (
+	timingTargetPage = "page"
+	timingTargetLink = "link"
+)
+
+var fetchPhaseDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "analyzer_fetch_phase_duration_seconds",
+		Help:    "Duration of each phase of the requests made while analyzing pages",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	},
+	[]string{"target", "phase"},
+)
+
+func init() {
+	prometheus.MustRegister(fetchPhaseDuration)
+}
+
+// requestTimer records the phases of a request through httptrace. Redirects
+// reuse the same trace, so connection phases add up across hops.
+type requestTimer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+	dns          time.Duration
+	connect      time.Duration
+	handshake    time.Duration
+	reused       bool
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+// trace returns a context that reports the request's progress to the timer
+func (t *requestTimer) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.elapse(&t.dnsStart, &t.dns)
+		},
+		ConnectStart: func(network, addr string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.elapse(&t.connectStart, &t.connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.elapse(&t.tlsStart, &t.handshake)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			// After a redirect only the final response counts
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.mu.Unlock()
+		},
+	})
+}
+
+func (t *requestTimer) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Concurrent dials for the same request share the earliest start
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+func (t *requestTimer) elapse(since *time.Time, total *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !since.IsZero() {
+		*total += time.Since(*since)
+		*since = time.Time{}
+	}
+}
+
+// finish is called once the body has been read; it returns the timing and
+// records every phase that happened in the histogram for target
+func (t *requestTimer) finish(target string) *types.RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := time.Now()
+
+	var ttfb, download time.Duration
+	if !t.firstByte.IsZero() {
+		ttfb = t.firstByte.Sub(t.start)
+		download = end.Sub(t.firstByte)
+	}
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"dns", t.dns},
+		{"connect", t.connect},
+		{"tls", t.handshake},
+		{"ttfb", ttfb},
+		{"download", download},
+		{"total", end.Sub(t.start)},
+	}
+	for _, phase := range phases {
+		if phase.duration > 0 {
+			fetchPhaseDuration.WithLabelValues(target, phase.name).Observe(phase.duration.Seconds())
+		}
+	}
+
+	return &types.RequestTiming{
+		DNSLookup:        milliseconds(t.dns),
+		TCPConnect:       milliseconds(t.connect),
+		TLSHandshake:     milliseconds(t.handshake),
+		TimeToFirstByte:  milliseconds(ttfb),
+		Download:         milliseconds(download),
+		Total:            milliseconds(end.Sub(t.start)),
+		ConnectionReused: t.reused,
+	}
+}
+
+// milliseconds converts a duration to milliseconds rounded to two decimals
+func milliseconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*100000) / 100
+}
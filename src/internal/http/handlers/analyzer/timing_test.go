@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_analyzePage_Timing(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><a href="` + "https://" + r.Host + `/other">Other</a></body></html>`))
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+	withTrustedServer(t, ts)
+
+	before := phaseSampleCount(t, timingTargetPage, "tls")
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+
+	got := result.Timing
+	if assert.NotNil(t, got) {
+		assert.Zero(t, got.DNSLookup, "no lookup happens for an IP literal")
+		assert.Greater(t, got.TCPConnect, 0.0)
+		assert.Greater(t, got.TLSHandshake, 0.0)
+		assert.Greater(t, got.TimeToFirstByte, 0.0)
+		assert.GreaterOrEqual(t, got.Download, 20.0)
+		assert.GreaterOrEqual(t, got.Total+0.02, got.TimeToFirstByte+got.Download, "phases are rounded separately")
+		assert.False(t, got.ConnectionReused)
+	}
+
+	assert.Greater(t, testutil.CollectAndCount(fetchPhaseDuration), 0)
+	assert.Equal(t, before+1, phaseSampleCount(t, timingTargetPage, "tls"))
+}
+
+func phaseSampleCount(t *testing.T, target, phase string) uint64 {
+	var metric dto.Metric
+	histogram := fetchPhaseDuration.WithLabelValues(target, phase).(prometheus.Histogram)
+	assert.NoError(t, histogram.Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func Test_checkLink_Timing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	status, timing := checkLink(ts.URL, defaultOptions(ts.URL))
+	assert.Equal(t, types.LinkStatusAccessible, status)
+	if assert.NotNil(t, timing) {
+		assert.Greater(t, timing.TimeToFirstByte, 0.0)
+	}
+
+	status, timing = checkLink(ts.URL+"/missing", defaultOptions(ts.URL))
+	assert.Equal(t, types.LinkStatusBroken, status)
+	if assert.NotNil(t, timing) {
+		assert.True(t, timing.ConnectionReused, "the keep-alive connection from the first check is reused")
+		assert.Zero(t, timing.TCPConnect)
+	}
+
+	status, timing = checkLink("http://127.0.0.1:0/", defaultOptions(ts.URL))
+	assert.Equal(t, types.LinkStatusBroken, status)
+	assert.Nil(t, timing)
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+// Certificate expiry thresholds in days
+const (
+	certExpiryWarningDays = 14
+	certExpiryNoticeDays  = 30
+)
+
+// TLS error kinds
+const (
+	TLSErrorSelfSigned       = "self-signed"
+	TLSErrorUntrusted        = "untrusted"
+	TLSErrorHostnameMismatch = "hostname-mismatch"
+	TLSErrorExpired          = "expired"
+	TLSErrorHandshake        = "handshake"
+)
+
+// TLSError is returned when the page could not be fetched because the TLS
+// handshake or certificate verification failed
+type TLSError struct {
+	Kind string
+	// Certificate is the offending certificate, when the failure names one
+	Certificate *x509.Certificate
+	Err         error
+}
+
+func (e *TLSError) Error() string {
+	if e.Kind == TLSErrorHandshake {
+		return fmt.Sprintf("TLS handshake failed: %v", e.Err)
+	}
+	return fmt.Sprintf("TLS certificate error (%s): %v", e.Kind, e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// classifyTLSError maps a fetch error onto a TLSError, or returns nil when the
+// failure is unrelated to TLS
+func classifyTLSError(err error) *TLSError {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		alert            tls.AlertError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority):
+		kind := TLSErrorUntrusted
+		if unknownAuthority.Cert != nil && isSelfSigned(unknownAuthority.Cert) {
+			kind = TLSErrorSelfSigned
+		}
+		return &TLSError{Kind: kind, Certificate: unknownAuthority.Cert, Err: unknownAuthority}
+	case errors.As(err, &hostname):
+		return &TLSError{Kind: TLSErrorHostnameMismatch, Certificate: hostname.Certificate, Err: hostname}
+	case errors.As(err, &invalid):
+		kind := TLSErrorUntrusted
+		if invalid.Reason == x509.Expired {
+			kind = TLSErrorExpired
+		}
+		return &TLSError{Kind: kind, Certificate: invalid.Cert, Err: invalid}
+	case errors.As(err, &recordHeader):
+		return &TLSError{Kind: TLSErrorHandshake, Err: errors.New("server did not respond with TLS")}
+	case errors.As(err, &alert):
+		return &TLSError{Kind: TLSErrorHandshake, Err: alert}
+	}
+	return nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// inspectTLS reports on the TLS connection the page was served over; it returns
+// nil for plain HTTP responses
+func inspectTLS(resp *http.Response) *types.TLSReport {
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	logrus.Debug("Inspecting TLS connection")
+	state := resp.TLS
+	leaf := state.PeerCertificates[0]
+
+	report := &types.TLSReport{
+		Version:         tls.VersionName(state.Version),
+		CipherSuite:     tls.CipherSuiteName(state.CipherSuite),
+		ServerName:      resp.Request.URL.Hostname(),
+		DaysUntilExpiry: int(time.Until(leaf.NotAfter).Hours() / 24),
+		Chain:           []types.CertificateInfo{},
+		Findings:        []types.Finding{},
+	}
+	report.HostnameMatch = leaf.VerifyHostname(report.ServerName) == nil
+	for _, cert := range state.PeerCertificates {
+		report.Chain = append(report.Chain, describeCertificate(cert))
+	}
+
+	checkTLS(state, leaf, report)
+	logrus.Debug("TLS inspected: ", report.Version, " ", report.CipherSuite)
+	return report
+}
+
+func describeCertificate(cert *x509.Certificate) types.CertificateInfo {
+	info := types.CertificateInfo{
+		Subject:    cert.Subject.String(),
+		Issuer:     cert.Issuer.String(),
+		DNSNames:   cert.DNSNames,
+		NotBefore:  cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:   cert.NotAfter.UTC().Format(time.RFC3339),
+		SelfSigned: isSelfSigned(cert),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddrs = append(info.IPAddrs, ip.String())
+	}
+	return info
+}
+
+func checkTLS(state *tls.ConnectionState, leaf *x509.Certificate, report *types.TLSReport) {
+	if state.Version < tls.VersionTLS12 {
+		addFinding(&report.Findings, types.SeverityError, "tls-version", "%s is deprecated, TLS 1.2 or later is required", report.Version)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == state.CipherSuite {
+			addFinding(&report.Findings, types.SeverityWarning, "tls-cipher", "Cipher suite %s is considered insecure", suite.Name)
+		}
+	}
+
+	if !report.HostnameMatch {
+		kind := "name"
+		if net.ParseIP(report.ServerName) != nil {
+			kind = "IP address"
+		}
+		addFinding(&report.Findings, types.SeverityError, "tls-hostname", "Certificate does not cover the %s %s", kind, report.ServerName)
+	}
+	if isSelfSigned(leaf) {
+		addFinding(&report.Findings, types.SeverityError, "tls-self-signed", "Certificate %q is self-signed", leaf.Subject.String())
+	}
+
+	switch days := report.DaysUntilExpiry; {
+	case time.Now().After(leaf.NotAfter):
+		addFinding(&report.Findings, types.SeverityError, "tls-expiry", "Certificate expired on %s", leaf.NotAfter.UTC().Format(time.DateOnly))
+	case days < certExpiryWarningDays:
+		addFinding(&report.Findings, types.SeverityWarning, "tls-expiry", "Certificate expires in %d days", days)
+	case days < certExpiryNoticeDays:
+		addFinding(&report.Findings, types.SeverityInfo, "tls-expiry", "Certificate expires in %d days", days)
+	}
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vinothnada/web-analyzer/internal/types"
+)
+
+func Test_classifyTLSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind string
+	}{
+		{name: "Hostname mismatch", err: fmt.Errorf("get: %w", x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}), kind: TLSErrorHostnameMismatch},
+		{name: "Expired", err: x509.CertificateInvalidError{Cert: &x509.Certificate{}, Reason: x509.Expired}, kind: TLSErrorExpired},
+		{name: "Unknown authority", err: x509.UnknownAuthorityError{}, kind: TLSErrorUntrusted},
+		{name: "Record header", err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, kind: TLSErrorHandshake},
+		{name: "Not TLS", err: errors.New("connection refused"), kind: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyTLSError(tt.err)
+			if tt.kind == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.kind, got.Kind)
+		})
+	}
+}
+
+func Test_fetchURL_SelfSignedCertificate(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	_, err := fetchURL(ts.URL)
+
+	var tlsErr *TLSError
+	if assert.ErrorAs(t, err, &tlsErr) {
+		assert.Equal(t, TLSErrorSelfSigned, tlsErr.Kind)
+		assert.NotNil(t, tlsErr.Certificate)
+	}
+}
+
+// withTrustedServer makes httpClient trust the certificate of ts for the
+// duration of the test, keeping the guarded transport and the credentials
+func withTrustedServer(t *testing.T, ts *httptest.Server) {
+	transport := guardedTransport()
+	transport.TLSClientConfig = ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	previous := httpClient
+	httpClient = &http.Client{Transport: newCredentialTransport(transport)}
+	t.Cleanup(func() { httpClient = previous })
+}
+
+func Test_inspectTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title>Secure</title></head></html>"))
+	}))
+	defer ts.Close()
+	withTrustedServer(t, ts)
+
+	result, err := analyzePage(ts.URL)
+	assert.NoError(t, err)
+
+	got := result.TLS
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "TLS 1.3", got.Version)
+		assert.NotEmpty(t, got.CipherSuite)
+		assert.Equal(t, "127.0.0.1", got.ServerName)
+		assert.True(t, got.HostnameMatch)
+		assert.Greater(t, got.DaysUntilExpiry, certExpiryNoticeDays)
+		assert.Len(t, got.Chain, 1)
+		assert.True(t, got.Chain[0].SelfSigned)
+		assert.Contains(t, got.Chain[0].IPAddrs, "127.0.0.1")
+		assert.True(t, hasFinding(got.Findings, types.SeverityError, "tls-self-signed"))
+		assert.False(t, hasFinding(got.Findings, types.SeverityError, "tls-hostname"))
+	}
+}
+
+func Test_inspectTLS_PlainHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Nil(t, inspectTLS(resp))
+}
@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts are dropped from canonical URLs
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// trackingParams are query parameters that only identify a campaign or click
+// and never change the page served; utm_* parameters are matched by prefix
+var trackingParams = toSet("fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "_gl")
+
+// normalizeURL validates an http or https URL and returns its canonical form:
+// lowercase scheme and host, IDN hosts in punycode, no trailing dot or default
+// port, dot segments resolved, percent-encoding normalized, tracking parameters
+// and the fragment removed. The canonical URL is what gets fetched, compared and reported.
+func normalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if strings.IndexFunc(rawURL, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", errors.New("the URL contains whitespace or control characters")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("the URL could not be parsed: %w", err)
+	}
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return "", errors.New("the URL must start with http:// or https://")
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", errors.New("the URL has no host")
+	}
+	if u.User != nil {
+		// Credentials in the URL would be echoed in results and logs
+		return "", errors.New("the URL must not contain credentials, configure them per host instead")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid port %q", port)
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	} else if strings.HasSuffix(u.Host, ":") {
+		return "", errors.New("the URL has an empty port")
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(u.Scheme + "://" + host)
+	path := removeDotSegments(normalizePercentEncoding(u.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	canonical.WriteString(path)
+	if query := stripTrackingParams(normalizePercentEncoding(u.RawQuery)); query != "" {
+		canonical.WriteString("?" + query)
+	}
+	return canonical.String(), nil
+}
+
+// normalizeHost lowercases the host, drops a trailing dot, converts
+// internationalized names to punycode and formats IP addresses canonically
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", errors.New("the URL has no host")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Is6() && !addr.Is4In6() {
+			return "[" + addr.String() + "]", nil
+		}
+		return addr.Unmap().String(), nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %w", host, err)
+	}
+	return ascii, nil
+}
+
+// normalizePercentEncoding uppercases percent escapes and decodes those that
+// encode unreserved characters, which are equivalent to the plain character
+func normalizePercentEncoding(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			out.WriteByte(s[i])
+			continue
+		}
+		decoded, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if c := byte(decoded); isUnreserved(c) {
+			out.WriteByte(c)
+		} else {
+			out.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return out.String()
+}
+
+func isHex(c byte) bool {
+	return strings.IndexByte("0123456789abcdefABCDEF", c) >= 0
+}
+
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~", c) >= 0
+}
+
+// removeDotSegments resolves "." and ".." path segments as RFC 3986 does
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		switch segment {
+		case ".":
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, segment)
+			continue
+		}
+		// A trailing dot segment still refers to a directory
+		if i == len(segments)-1 {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// stripTrackingParams removes tracking parameters and empty pairs from a raw
+// query, keeping the order of the remaining parameters
+func stripTrackingParams(query string) string {
+	var kept []string
+	for _, pair := range strings.Split(query, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		key = strings.ToLower(key)
+		if pair == "" || trackingParams[key] || strings.HasPrefix(key, "utm_") {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
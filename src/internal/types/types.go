@@ -1,16 +1,448 @@
 package types
 
 type AnalyzeResultes struct {
-	HTMLVersion             string         `json:"htmlVersion"`
-	Title                   string         `json:"title"`
-	Headings                map[string]int `json:"headings"`
-	InternalLinks           int            `json:"internalLinks"`
-	ExternalLinks           int            `json:"externalLinks"`
-	HasLoginForm            bool           `json:"hasLoginForm"`
-	AccessibleExternalLinks int            `json:"accessibleExternalLinks"`
-	BrokenExternalLinks     int            `json:"brokenExternalLinks"`
+	// URL is the canonical form of the analyzed URL
+	URL                     string                 `json:"url,omitempty"`
+	ContentType             string                 `json:"contentType,omitempty"`
+	HTMLVersion             string                 `json:"htmlVersion"`
+	Doctype                 *Doctype               `json:"doctype,omitempty"`
+	Title                   string                 `json:"title"`
+	Headings                map[string]int         `json:"headings"`
+	InternalLinks           int                    `json:"internalLinks"`
+	ExternalLinks           int                    `json:"externalLinks"`
+	HasLoginForm            bool                   `json:"hasLoginForm"`
+	Forms                   *FormsReport           `json:"forms,omitempty"`
+	AccessibleExternalLinks int                    `json:"accessibleExternalLinks"`
+	BrokenExternalLinks     int                    `json:"brokenExternalLinks"`
+	Links                   []LinkResult           `json:"links,omitempty"`
+	SEO                     *SEOReport             `json:"seo,omitempty"`
+	StructuredData          *StructuredDataReport  `json:"structuredData,omitempty"`
+	Accessibility           *AccessibilityReport   `json:"accessibility,omitempty"`
+	HeadingOutline          *HeadingOutline        `json:"headingOutline,omitempty"`
+	SecurityHeaders         *SecurityHeadersReport `json:"securityHeaders,omitempty"`
+	CSP                     *CSPReport             `json:"csp,omitempty"`
+	Cookies                 *CookieReport          `json:"cookies,omitempty"`
+	TLS                     *TLSReport             `json:"tls,omitempty"`
+	MixedContent            *MixedContentReport    `json:"mixedContent,omitempty"`
+	Resources               *ResourcesReport       `json:"resources,omitempty"`
+	PageWeight              *PageWeightReport      `json:"pageWeight,omitempty"`
+	Timing                  *RequestTiming         `json:"timing,omitempty"`
+	Encoding                *EncodingReport        `json:"encoding,omitempty"`
+	Document                *DocumentInfo          `json:"document,omitempty"`
+	Login                   *LoginReport           `json:"login,omitempty"`
+	// Truncated is set when limits cut the analysis short; the reasons say which
+	Truncated         bool     `json:"truncated,omitempty"`
+	TruncationReasons []string `json:"truncationReasons,omitempty"`
+}
+
+// Finding severities
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Finding is a single issue reported by one of the audits
+type Finding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+	Selector string `json:"selector,omitempty"`
+	WCAG     string `json:"wcag,omitempty"`
+}
+
+// Link types
+const (
+	LinkTypeInternal = "internal"
+	LinkTypeExternal = "external"
+)
+
+// Link statuses
+const (
+	LinkStatusAccessible = "accessible"
+	LinkStatusBroken     = "broken"
+	LinkStatusUnchecked  = "unchecked"
+)
+
+type LinkResult struct {
+	Href       string         `json:"href"`
+	Type       string         `json:"type"`
+	Status     string         `json:"status"`
+	AnchorText string         `json:"anchorText"`
+	Timing     *RequestTiming `json:"timing,omitempty"`
 }
 
 type RequestPayload struct {
 	URL string `json:"url"`
+	// PageWeight fetches every subresource to measure sizes, compression and caching
+	PageWeight bool `json:"pageWeight,omitempty"`
+
+	// The options below tune a single analysis; unset fields use the configured
+	// defaults and the server rejects values beyond its caps
+	UserAgent      string `json:"userAgent,omitempty"`
+	AcceptLanguage string `json:"acceptLanguage,omitempty"`
+	// Headers are sent with requests to the target's host only
+	Headers        map[string]string `json:"headers,omitempty"`
+	TimeoutSeconds float64           `json:"timeoutSeconds,omitempty"`
+	// External links are checked unless CheckExternalLinks is false
+	CheckExternalLinks *bool `json:"checkExternalLinks,omitempty"`
+	CheckInternalLinks bool  `json:"checkInternalLinks,omitempty"`
+	MaxLinks           int   `json:"maxLinks,omitempty"`
+	// Checks lists the checks to run, all of them when empty
+	Checks []string `json:"checks,omitempty"`
+	// Redirects are followed unless FollowRedirects is false
+	FollowRedirects *bool `json:"followRedirects,omitempty"`
+	// CookieJar keeps the cookies set during the analysis and sends them back
+	// on later requests; Cookies seeds the jar for the target and implies it
+	CookieJar bool              `json:"cookieJar,omitempty"`
+	Cookies   map[string]string `json:"cookies,omitempty"`
+	// Login names a configured login to perform first; it implies CookieJar
+	Login string `json:"login,omitempty"`
+}
+
+// LoginReport describes the login performed before the analysis
+type LoginReport struct {
+	Name     string `json:"name"`
+	FormURL  string `json:"formUrl"`
+	Action   string `json:"action"`
+	Status   int    `json:"status"`
+	FinalURL string `json:"finalUrl"`
+}
+
+type SEOReport struct {
+	Title             string            `json:"title"`
+	TitleLength       int               `json:"titleLength"`
+	MetaDescription   string            `json:"metaDescription"`
+	DescriptionLength int               `json:"descriptionLength"`
+	Canonical         string            `json:"canonical,omitempty"`
+	MetaRobots        string            `json:"metaRobots,omitempty"`
+	XRobotsTag        string            `json:"xRobotsTag,omitempty"`
+	Indexable         bool              `json:"indexable"`
+	OpenGraph         map[string]string `json:"openGraph"`
+	TwitterCard       map[string]string `json:"twitterCard"`
+	Hreflang          []HreflangLink    `json:"hreflang,omitempty"`
+	Next              string            `json:"next,omitempty"`
+	Prev              string            `json:"prev,omitempty"`
+	Findings          []Finding         `json:"findings"`
+}
+
+// Hreflang return-link statuses
+const (
+	ReturnLinkOK          = "ok"
+	ReturnLinkMissing     = "missing"
+	ReturnLinkUnreachable = "unreachable"
+	ReturnLinkSelf        = "self"
+	// ReturnLinkUnchecked marks alternates beyond the link cap of the analysis
+	ReturnLinkUnchecked = "unchecked"
+)
+
+type HreflangLink struct {
+	Lang       string `json:"lang"`
+	Href       string `json:"href"`
+	ReturnLink string `json:"returnLink"`
+}
+
+type StructuredDataReport struct {
+	JSONLD    []interface{}        `json:"jsonLd"`
+	Microdata []StructuredDataItem `json:"microdata"`
+	RDFa      []StructuredDataItem `json:"rdfa"`
+	Findings  []Finding            `json:"findings"`
+}
+
+// StructuredDataItem is a typed item extracted from microdata or RDFa markup.
+// Property values are strings or nested items.
+type StructuredDataItem struct {
+	Type       []string                 `json:"type"`
+	ID         string                   `json:"id,omitempty"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+type AccessibilityReport struct {
+	Findings []Finding `json:"findings"`
+}
+
+type HeadingOutline struct {
+	Headings []*HeadingNode `json:"headings"`
+	Findings []Finding      `json:"findings"`
+}
+
+// HeadingNode is a heading with the lower-level headings nested under it
+type HeadingNode struct {
+	Level    int            `json:"level"`
+	Text     string         `json:"text"`
+	Children []*HeadingNode `json:"children,omitempty"`
+}
+
+// Browser rendering modes
+const (
+	RenderingModeStandards       = "standards"
+	RenderingModeAlmostStandards = "almost-standards"
+	RenderingModeQuirks          = "quirks"
+)
+
+type Doctype struct {
+	Present  bool   `json:"present"`
+	Name     string `json:"name,omitempty"`
+	PublicID string `json:"publicId,omitempty"`
+	SystemID string `json:"systemId,omitempty"`
+	Version  string `json:"version"`
+	Mode     string `json:"mode"`
+}
+
+// Form classifications
+const (
+	FormLogin         = "login"
+	FormSignup        = "signup"
+	FormPasswordReset = "password-reset"
+	FormSearch        = "search"
+	FormNewsletter    = "newsletter"
+	FormPayment       = "payment"
+	FormContact       = "contact"
+	FormUnknown       = "unknown"
+)
+
+type FormsReport struct {
+	Forms          []FormInfo `json:"forms"`
+	OAuthProviders []string   `json:"oauthProviders,omitempty"`
+	Findings       []Finding  `json:"findings"`
+}
+
+type FormInfo struct {
+	Selector       string      `json:"selector"`
+	Implicit       bool        `json:"implicit,omitempty"`
+	Method         string      `json:"method"`
+	Action         string      `json:"action"`
+	Fields         []FormField `json:"fields"`
+	CSRFTokens     []string    `json:"csrfTokens,omitempty"`
+	Classification string      `json:"classification"`
+	Confidence     float64     `json:"confidence"`
+	Evidence       []string    `json:"evidence"`
+}
+
+type FormField struct {
+	Name         string `json:"name,omitempty"`
+	Type         string `json:"type"`
+	Autocomplete string `json:"autocomplete,omitempty"`
+	Required     bool   `json:"required,omitempty"`
+}
+
+type SecurityHeadersReport struct {
+	Headers  map[string]string `json:"headers"`
+	HSTS     *HSTSInfo         `json:"hsts,omitempty"`
+	Score    int               `json:"score"`
+	Grade    string            `json:"grade"`
+	Findings []Finding         `json:"findings"`
+}
+
+type HSTSInfo struct {
+	MaxAge            int64 `json:"maxAge"`
+	IncludeSubDomains bool  `json:"includeSubDomains"`
+	Preload           bool  `json:"preload"`
+	PreloadEligible   bool  `json:"preloadEligible"`
+}
+
+// Content-Security-Policy delivery sources
+const (
+	CSPSourceHeader           = "header"
+	CSPSourceHeaderReportOnly = "header-report-only"
+	CSPSourceMeta             = "meta"
+)
+
+type CSPReport struct {
+	Policies             []CSPPolicy `json:"policies"`
+	BlockedInlineScripts int         `json:"blockedInlineScripts"`
+	BlockedEventHandlers int         `json:"blockedEventHandlers"`
+	BlockedScriptOrigins []string    `json:"blockedScriptOrigins,omitempty"`
+	Findings             []Finding   `json:"findings"`
+}
+
+type CSPPolicy struct {
+	Source     string              `json:"source"`
+	ReportOnly bool                `json:"reportOnly"`
+	Directives map[string][]string `json:"directives"`
+}
+
+type CookieReport struct {
+	Cookies  []CookieInfo `json:"cookies"`
+	Findings []Finding    `json:"findings"`
+}
+
+type CookieInfo struct {
+	Name     string `json:"name"`
+	SetBy    string `json:"setBy"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"httpOnly"`
+	SameSite string `json:"sameSite,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	// LifetimeSeconds is 0 for session cookies and negative for deletions
+	LifetimeSeconds int64 `json:"lifetimeSeconds"`
+}
+
+type TLSReport struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ServerName  string `json:"serverName"`
+	// HostnameMatch reports whether the leaf certificate covers ServerName
+	HostnameMatch   bool              `json:"hostnameMatch"`
+	DaysUntilExpiry int               `json:"daysUntilExpiry"`
+	Chain           []CertificateInfo `json:"chain"`
+	Findings        []Finding         `json:"findings"`
+}
+
+type CertificateInfo struct {
+	Subject    string   `json:"subject"`
+	Issuer     string   `json:"issuer"`
+	DNSNames   []string `json:"dnsNames,omitempty"`
+	IPAddrs    []string `json:"ipAddresses,omitempty"`
+	NotBefore  string   `json:"notBefore"`
+	NotAfter   string   `json:"notAfter"`
+	SelfSigned bool     `json:"selfSigned"`
+}
+
+// Mixed content categories
+const (
+	MixedContentActive  = "active"
+	MixedContentPassive = "passive"
+	// MixedContentForm is a form submitting over HTTP, which browsers warn about instead of blocking
+	MixedContentForm = "form"
+)
+
+type MixedContentReport struct {
+	// UpgradeInsecureRequests is set when an enforced CSP upgrades the requests
+	UpgradeInsecureRequests bool               `json:"upgradeInsecureRequests"`
+	Active                  int                `json:"active"`
+	Passive                 int                `json:"passive"`
+	Forms                   int                `json:"forms"`
+	Items                   []MixedContentItem `json:"items"`
+	Findings                []Finding          `json:"findings"`
+}
+
+type MixedContentItem struct {
+	URL       string `json:"url"`
+	Element   string `json:"element"`
+	Attribute string `json:"attribute,omitempty"`
+	Category  string `json:"category"`
+	Selector  string `json:"selector"`
+}
+
+// Subresource kinds
+const (
+	ResourceScript     = "script"
+	ResourceStylesheet = "stylesheet"
+	ResourceImage      = "image"
+	ResourceFont       = "font"
+	ResourceFrame      = "frame"
+	ResourceMedia      = "media"
+	ResourcePreload    = "preload"
+	ResourceOther      = "other"
+	ResourceDocument   = "document"
+)
+
+type ResourcesReport struct {
+	Resources  []Resource      `json:"resources"`
+	FirstParty int             `json:"firstParty"`
+	ThirdParty int             `json:"thirdParty"`
+	Domains    []DomainSummary `json:"domains"`
+}
+
+type Resource struct {
+	URL        string `json:"url"`
+	Kind       string `json:"kind"`
+	Element    string `json:"element"`
+	Domain     string `json:"domain"`
+	ThirdParty bool   `json:"thirdParty"`
+}
+
+type DomainSummary struct {
+	Domain     string         `json:"domain"`
+	ThirdParty bool           `json:"thirdParty"`
+	Count      int            `json:"count"`
+	Kinds      map[string]int `json:"kinds"`
+}
+
+type PageWeightReport struct {
+	// AcceptEncoding is what the requests offered, transfer sizes can be larger
+	// than what browsers download from servers that prefer encodings not listed
+	AcceptEncoding string `json:"acceptEncoding"`
+	// Partial is set when subresources are left out of the totals, the
+	// findings say why
+	Partial           bool                    `json:"partial,omitempty"`
+	Requests          int                     `json:"requests"`
+	TotalTransferSize int64                   `json:"totalTransferSize"`
+	TotalDecodedSize  int64                   `json:"totalDecodedSize"`
+	ByKind            map[string]WeightTotals `json:"byKind"`
+	Largest           []ResourceWeight        `json:"largest"`
+	Resources         []ResourceWeight        `json:"resources"`
+	Findings          []Finding               `json:"findings"`
+}
+
+type WeightTotals struct {
+	Count        int   `json:"count"`
+	TransferSize int64 `json:"transferSize"`
+	DecodedSize  int64 `json:"decodedSize"`
+}
+
+type ResourceWeight struct {
+	URL         string `json:"url"`
+	Kind        string `json:"kind"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	// TransferSize is the body size on the wire, DecodedSize after removing the
+	// content encoding, or -1 when the encoding could not be decoded
+	TransferSize    int64  `json:"transferSize"`
+	DecodedSize     int64  `json:"decodedSize"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	CacheControl    string `json:"cacheControl,omitempty"`
+	ETag            string `json:"etag,omitempty"`
+	// MaxAge is the freshness lifetime in seconds, or -1 when none is declared
+	MaxAge int64 `json:"maxAge"`
+	// Truncated is why the body was not read to the end, in which case the
+	// sizes cover only the part read
+	Truncated string `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RequestTiming breaks a request down into phases, in milliseconds. Phases that
+// did not happen, such as DNS for a reused connection, are zero.
+type RequestTiming struct {
+	DNSLookup        float64 `json:"dnsLookupMs"`
+	TCPConnect       float64 `json:"tcpConnectMs"`
+	TLSHandshake     float64 `json:"tlsHandshakeMs"`
+	TimeToFirstByte  float64 `json:"timeToFirstByteMs"`
+	Download         float64 `json:"downloadMs"`
+	Total            float64 `json:"totalMs"`
+	ConnectionReused bool    `json:"connectionReused"`
+}
+
+// Where the character encoding of a page was taken from
+const (
+	EncodingSourceBOM     = "bom"
+	EncodingSourceHeader  = "header"
+	EncodingSourceMeta    = "meta"
+	EncodingSourceSniffed = "sniffed"
+)
+
+type EncodingReport struct {
+	// Encoding is the canonical name of the encoding the page was decoded with
+	Encoding      string    `json:"encoding"`
+	Source        string    `json:"source"`
+	BOM           string    `json:"bom,omitempty"`
+	HeaderCharset string    `json:"headerCharset,omitempty"`
+	MetaCharset   string    `json:"metaCharset,omitempty"`
+	Findings      []Finding `json:"findings"`
+}
+
+// DocumentInfo holds the metadata of non-HTML targets such as PDFs
+type DocumentInfo struct {
+	Format   string `json:"format"`
+	Version  string `json:"version,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Creator  string `json:"creator,omitempty"`
+	Producer string `json:"producer,omitempty"`
+	Pages    int    `json:"pages,omitempty"`
+	Lines    int    `json:"lines,omitempty"`
+	Words    int    `json:"words,omitempty"`
 }
@@ -35,6 +35,17 @@ func requestMetricsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// serverWriteTimeout leaves a response time for the page fetch and the link
+// checks after it to each run into the longest timeout a request may ask for,
+// plus a margin for the rest of the analysis. Without a maximum timeout
+// responses are not cut off.
+func serverWriteTimeout(cfg config.Analyzer) time.Duration {
+	if cfg.MaxTimeout <= 0 {
+		return 0
+	}
+	return 2*max(cfg.MaxTimeout, cfg.DefaultTimeout) + 30*time.Second
+}
+
 func main() {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -46,6 +57,19 @@ func main() {
 	logger.WithFields(logrus.Fields{
 		"address": cfg.Addr,
 	}).Info("Configuration loaded")
+	analyzer.Configure(cfg.Analyzer)
+	if err := analyzer.ConfigureSSRF(cfg.SSRF); err != nil {
+		logger.Fatal("Invalid SSRF configuration: ", err)
+	}
+	if err := analyzer.ConfigureNetwork(cfg.Network); err != nil {
+		logger.Fatal("Invalid network configuration: ", err)
+	}
+	if err := analyzer.ConfigureCredentials(cfg.Credentials); err != nil {
+		logger.Fatal("Invalid credentials configuration: ", err)
+	}
+	if err := analyzer.ConfigureLogins(cfg.Logins); err != nil {
+		logger.Fatal("Invalid logins configuration: ", err)
+	}
 
 	// Initialize the router
 	router := http.NewServeMux()
@@ -53,6 +77,9 @@ func main() {
 
 	// Register handlers
 	router.HandleFunc("/api/analyze", analyzer.GetResults)
+	router.HandleFunc("/api/export/metrics.csv", analyzer.ExportMetricsCSV)
+	router.HandleFunc("/api/export/links.csv", analyzer.ExportLinksCSV)
+	router.HandleFunc("/api/export/bundle.zip", analyzer.ExportBundle)
 	router.Handle("/metrics", promhttp.Handler())
 	router.HandleFunc("/debug/pprof/", http.DefaultServeMux.ServeHTTP) // Enable pprof
 
@@ -63,7 +90,7 @@ func main() {
 		Addr:           cfg.Addr,
 		Handler:        requestMetricsMiddleware(router),
 		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+		WriteTimeout:   serverWriteTimeout(cfg.Analyzer),
 		MaxHeaderBytes: 1 << 20,
 	}
 